@@ -18,6 +18,8 @@
 package env
 
 import (
+	"fmt"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -25,9 +27,10 @@ import (
 )
 
 var (
-	privateMutex sync.RWMutex
-	lockEnvMutex sync.Mutex
-	envOff       bool
+	privateMutex    sync.RWMutex
+	lockEnvMutex    sync.Mutex
+	envOff          bool
+	deprecationHook func(used, preferred string)
 )
 
 // LockSetEnv locks modifications to environment.
@@ -78,6 +81,59 @@ func Get(key, defaultValue string) string {
 	return strings.TrimSpace(defaultValue)
 }
 
+// Lookup retrieves the value of the environment variable named by
+// the key, with surrounding whitespace trimmed like Get. Unlike Get,
+// it reports whether the variable is present, so that a variable set
+// to an empty value can be told apart from an unset one. Variables
+// are reported unset while env lookup is turned off.
+func Lookup(key string) (string, bool) {
+	privateMutex.RLock()
+	off := envOff
+	privateMutex.RUnlock()
+	if off {
+		return "", false
+	}
+	if _, ok := os.LookupEnv(key); !ok {
+		return "", false
+	}
+	v, _, _, _ := LookupEnv(key)
+	return strings.TrimSpace(v), true
+}
+
+// GetWithFallbackKeys returns the value of the first of the given
+// environment variables that is set, or the default value if none
+// is. The first key is the preferred name, the next ones are older
+// names still honored; reading a value from one of them calls the
+// hook set by SetDeprecationHook, if any.
+func GetWithFallbackKeys(defaultValue string, keys ...string) string {
+	for i, key := range keys {
+		v := Get(key, "")
+		if v == "" {
+			continue
+		}
+		if i > 0 {
+			privateMutex.RLock()
+			hook := deprecationHook
+			privateMutex.RUnlock()
+			if hook != nil {
+				hook(key, keys[0])
+			}
+		}
+		return v
+	}
+	return strings.TrimSpace(defaultValue)
+}
+
+// SetDeprecationHook sets the function called by GetWithFallbackKeys
+// with the deprecated key used and its preferred replacement, e.g.
+// to log a warning. A nil fn removes the hook.
+func SetDeprecationHook(fn func(used, preferred string)) {
+	privateMutex.Lock()
+	defer privateMutex.Unlock()
+
+	deprecationHook = fn
+}
+
 // GetInt returns an integer if found in the environment
 // and returns the default value otherwise.
 func GetInt(key string, defaultValue int) (int, error) {
@@ -88,6 +144,87 @@ func GetInt(key string, defaultValue int) (int, error) {
 	return strconv.Atoi(v)
 }
 
+// GetInt64 returns the 64-bit integer value of the environment
+// variable. The default value is returned if it is unset or not a
+// 64-bit integer, use LookupInt64 to report invalid values.
+func GetInt64(key string, defaultValue int64) int64 {
+	if i, ok, err := LookupInt64(key); ok && err == nil {
+		return i
+	}
+	return defaultValue
+}
+
+// LookupInt returns the integer value of the environment variable
+// and whether it is set. An error is returned if it is set but
+// isn't an integer.
+func LookupInt(key string) (int, bool, error) {
+	v := Get(key, "")
+	if v == "" {
+		return 0, false, nil
+	}
+	i, err := strconv.Atoi(v)
+	return i, true, err
+}
+
+// LookupInt64 returns the 64-bit integer value of the environment
+// variable and whether it is set. An error is returned if it is set
+// but isn't a 64-bit integer.
+func LookupInt64(key string) (int64, bool, error) {
+	v := Get(key, "")
+	if v == "" {
+		return 0, false, nil
+	}
+	i, err := strconv.ParseInt(v, 10, 64)
+	return i, true, err
+}
+
+// LookupFloat64 returns the floating-point value of the environment
+// variable and whether it is set. The value is parsed by strconv.ParseFloat,
+// independently of the locale, e.g. "0.8" or "1e3". An error is returned if
+// it is set but isn't a number.
+func LookupFloat64(key string) (float64, bool, error) {
+	v := Get(key, "")
+	if v == "" {
+		return 0, false, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	return f, true, err
+}
+
+// GetFloat64 returns the floating-point value of the environment variable,
+// see LookupFloat64 for the accepted values. The default value is returned
+// if it is unset or not a number.
+func GetFloat64(key string, defaultValue float64) float64 {
+	if f, ok, err := LookupFloat64(key); ok && err == nil {
+		return f
+	}
+	return defaultValue
+}
+
+// LookupBool returns the boolean value of the environment variable
+// and whether it is set to a boolean. The accepted values, case
+// insensitive, are "1", "t", "true", "on" and "yes" for true and
+// "0", "f", "false", "off" and "no" for false.
+func LookupBool(key string) (bool, bool) {
+	switch strings.ToLower(Get(key, "")) {
+	case "1", "t", "true", "on", "yes":
+		return true, true
+	case "0", "f", "false", "off", "no":
+		return false, true
+	}
+	return false, false
+}
+
+// GetBool returns the boolean value of the environment variable,
+// see LookupBool for the accepted values. The default value is
+// returned if it is unset or not a boolean.
+func GetBool(key string, defaultValue bool) bool {
+	if v, ok := LookupBool(key); ok {
+		return v
+	}
+	return defaultValue
+}
+
 // GetDuration returns a parsed time.Duration if found in
 // the environment value, returns the default value duration
 // otherwise.
@@ -99,6 +236,51 @@ func GetDuration(key string, defaultValue time.Duration) (time.Duration, error)
 	return time.ParseDuration(v)
 }
 
+// LookupDuration returns the time.Duration value of the environment
+// variable and whether it is set. An error is returned if it is set
+// but isn't a valid duration.
+func LookupDuration(key string) (time.Duration, bool, error) {
+	v := Get(key, "")
+	if v == "" {
+		return 0, false, nil
+	}
+	d, err := time.ParseDuration(v)
+	return d, true, err
+}
+
+// GetStringSlice returns the comma separated values of the
+// environment variable, with surrounding whitespace trimmed and
+// empty values dropped. The default value is returned if it is
+// unset.
+func GetStringSlice(key string, defaultValue []string) []string {
+	v := Get(key, "")
+	if v == "" {
+		return defaultValue
+	}
+	var values []string
+	for _, value := range strings.Split(v, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Require returns an error naming all the given environment
+// variables that are unset or empty.
+func Require(keys ...string) error {
+	var missing []string
+	for _, key := range keys {
+		if !IsSet(key) {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required environment variables: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
 // List all envs with a given prefix.
 func List(prefix string) (envs []string) {
 	for _, env := range Environ() {
@@ -22,6 +22,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"reflect"
 	"testing"
 	"time"
 
@@ -165,3 +166,293 @@ func TestGetEnv(t *testing.T) {
 		t.Fatalf("Expected 'value-new', but got %s", v)
 	}
 }
+
+func TestGetInt64(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected int64
+	}{
+		{"", 7},
+		{"42", 42},
+		{" -9223372036854775808 ", -9223372036854775808},
+		{"9223372036854775808", 7},
+		{"4.2", 7},
+		{"forty-two", 7},
+	}
+	for i, testCase := range testCases {
+		t.Setenv("_TEST_ENV", testCase.value)
+		if v := GetInt64("_TEST_ENV", 7); v != testCase.expected {
+			t.Fatalf("%d: Expected %d but got %d", i+1, testCase.expected, v)
+		}
+	}
+}
+
+func TestLookupInt(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected int
+		set      bool
+		isErr    bool
+	}{
+		{"", 0, false, false},
+		{"42", 42, true, false},
+		{"forty-two", 0, true, true},
+	}
+	for i, testCase := range testCases {
+		t.Setenv("_TEST_ENV", testCase.value)
+		v, ok, err := LookupInt("_TEST_ENV")
+		if testCase.isErr != (err != nil) {
+			t.Fatalf("%d: Expected error %v but got %v", i+1, testCase.isErr, err)
+		}
+		if v != testCase.expected || ok != testCase.set {
+			t.Fatalf("%d: Expected %d, %v but got %d, %v", i+1, testCase.expected, testCase.set, v, ok)
+		}
+	}
+}
+
+func TestLookupInt64(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected int64
+		set      bool
+		isErr    bool
+	}{
+		{"", 0, false, false},
+		{"42", 42, true, false},
+		{" -9223372036854775808 ", -9223372036854775808, true, false},
+		{"9223372036854775808", 9223372036854775807, true, true},
+		{"4.2", 0, true, true},
+	}
+	for i, testCase := range testCases {
+		t.Setenv("_TEST_ENV", testCase.value)
+		v, ok, err := LookupInt64("_TEST_ENV")
+		if testCase.isErr != (err != nil) {
+			t.Fatalf("%d: Expected error %v but got %v", i+1, testCase.isErr, err)
+		}
+		if v != testCase.expected || ok != testCase.set {
+			t.Fatalf("%d: Expected %d, %v but got %d, %v", i+1, testCase.expected, testCase.set, v, ok)
+		}
+	}
+}
+
+func TestLookupFloat64(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected float64
+		set      bool
+		isErr    bool
+	}{
+		{"0.8", 0.8, true, false},
+		{"1e3", 1000, true, false},
+		{"-2", -2, true, false},
+		{"", 0, false, false},
+		{"abc", 0, true, true},
+		{"0,8", 0, true, true},
+	}
+	for i, testCase := range testCases {
+		t.Setenv("_TEST_ENV", testCase.value)
+		f, ok, err := LookupFloat64("_TEST_ENV")
+		if testCase.isErr != (err != nil) {
+			t.Fatalf("%d: Expected error %v but got %v", i+1, testCase.isErr, err)
+		}
+		if f != testCase.expected || ok != testCase.set {
+			t.Fatalf("%d: Expected %v, %v but got %v, %v", i+1, testCase.expected, testCase.set, f, ok)
+		}
+
+		expected := testCase.expected
+		if !testCase.set || testCase.isErr {
+			expected = 0.5
+		}
+		if f = GetFloat64("_TEST_ENV", 0.5); f != expected {
+			t.Fatalf("%d: Expected %v but got %v", i+1, expected, f)
+		}
+	}
+}
+
+func TestGetBool(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected bool
+		set      bool
+	}{
+		{"", false, false},
+		{"1", true, true},
+		{"t", true, true},
+		{"TRUE", true, true},
+		{"True", true, true},
+		{" on ", true, true},
+		{"Yes", true, true},
+		{"0", false, true},
+		{"F", false, true},
+		{"false", false, true},
+		{"OFF", false, true},
+		{"no", false, true},
+		{"enabled", false, false},
+		{"2", false, false},
+	}
+	for i, testCase := range testCases {
+		t.Setenv("_TEST_ENV", testCase.value)
+		v, ok := LookupBool("_TEST_ENV")
+		if v != testCase.expected || ok != testCase.set {
+			t.Fatalf("%d: Expected %v, %v but got %v, %v", i+1, testCase.expected, testCase.set, v, ok)
+		}
+		for _, def := range []bool{false, true} {
+			expected := testCase.expected
+			if !testCase.set {
+				expected = def
+			}
+			if v := GetBool("_TEST_ENV", def); v != expected {
+				t.Fatalf("%d: Expected %v with default %v but got %v", i+1, expected, def, v)
+			}
+		}
+	}
+}
+
+func TestLookupDuration(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected time.Duration
+		set      bool
+		isErr    bool
+	}{
+		{"30s", 30 * time.Second, true, false},
+		{"5m", 5 * time.Minute, true, false},
+		{"", 0, false, false},
+		{"garbage", 0, true, true},
+	}
+	for i, testCase := range testCases {
+		t.Setenv("_TEST_ENV", testCase.value)
+		d, ok, err := LookupDuration("_TEST_ENV")
+		if testCase.isErr != (err != nil) {
+			t.Fatalf("%d: Expected error %v but got %v", i+1, testCase.isErr, err)
+		}
+		if d != testCase.expected || ok != testCase.set {
+			t.Fatalf("%d: Expected %s, %v but got %s, %v", i+1, testCase.expected, testCase.set, d, ok)
+		}
+
+		d, err = GetDuration("_TEST_ENV", time.Hour)
+		if testCase.isErr != (err != nil) {
+			t.Fatalf("%d: Expected error %v but got %v", i+1, testCase.isErr, err)
+		}
+		if expected := testCase.expected; !testCase.isErr {
+			if !testCase.set {
+				expected = time.Hour
+			}
+			if d != expected {
+				t.Fatalf("%d: Expected %s but got %s", i+1, expected, d)
+			}
+		}
+	}
+}
+
+func TestGetStringSlice(t *testing.T) {
+	def := []string{"default"}
+	testCases := []struct {
+		value    string
+		expected []string
+	}{
+		{"", def},
+		{"abc123", []string{"abc123"}},
+		{"abc123,def456", []string{"abc123", "def456"}},
+		{" abc123 , def456 ,ghi789", []string{"abc123", "def456", "ghi789"}},
+		{"abc123,,def456,", []string{"abc123", "def456"}},
+		{" , ,", nil},
+	}
+	for i, testCase := range testCases {
+		t.Setenv("_TEST_ENV", testCase.value)
+		if v := GetStringSlice("_TEST_ENV", def); !reflect.DeepEqual(v, testCase.expected) {
+			t.Fatalf("%d: Expected %q but got %q", i+1, testCase.expected, v)
+		}
+	}
+
+	t.Setenv("_TEST_ENV", "")
+	if v := GetStringSlice("_TEST_ENV", nil); v != nil {
+		t.Fatalf("Expected nil but got %q", v)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	t.Setenv("_TEST_ENV_SET", " value ")
+	t.Setenv("_TEST_ENV_EMPTY", "")
+
+	testCases := []struct {
+		key      string
+		expected string
+		ok       bool
+	}{
+		{"_TEST_ENV_SET", "value", true},
+		{"_TEST_ENV_EMPTY", "", true},
+		{"_TEST_ENV_UNSET", "", false},
+	}
+	for i, testCase := range testCases {
+		v, ok := Lookup(testCase.key)
+		if v != testCase.expected || ok != testCase.ok {
+			t.Fatalf("%d: Expected %q, %v but got %q, %v", i+1, testCase.expected, testCase.ok, v, ok)
+		}
+	}
+
+	SetEnvOff()
+	defer SetEnvOn()
+	if v, ok := Lookup("_TEST_ENV_SET"); ok || v != "" {
+		t.Fatalf("Expected unset variable with env lookup off but got %q, %v", v, ok)
+	}
+}
+
+func TestGetWithFallbackKeys(t *testing.T) {
+	type use struct{ used, preferred string }
+	var uses []use
+	SetDeprecationHook(func(used, preferred string) {
+		uses = append(uses, use{used, preferred})
+	})
+	defer SetDeprecationHook(nil)
+
+	t.Setenv("_TEST_ENV_NEW", "new")
+	t.Setenv("_TEST_ENV_OLD", "old")
+	t.Setenv("_TEST_ENV_EMPTY", "")
+
+	testCases := []struct {
+		keys     []string
+		expected string
+		uses     []use
+	}{
+		{[]string{"_TEST_ENV_NEW", "_TEST_ENV_OLD"}, "new", nil},
+		{[]string{"_TEST_ENV_UNSET", "_TEST_ENV_EMPTY", "_TEST_ENV_OLD"}, "old", []use{{"_TEST_ENV_OLD", "_TEST_ENV_UNSET"}}},
+		{[]string{"_TEST_ENV_UNSET", "_TEST_ENV_EMPTY"}, "default", nil},
+		{nil, "default", nil},
+	}
+	for i, testCase := range testCases {
+		uses = nil
+		if v := GetWithFallbackKeys("default", testCase.keys...); v != testCase.expected {
+			t.Fatalf("%d: Expected %q but got %q", i+1, testCase.expected, v)
+		}
+		if !reflect.DeepEqual(uses, testCase.uses) {
+			t.Fatalf("%d: Expected deprecated uses %v but got %v", i+1, testCase.uses, uses)
+		}
+	}
+
+	SetDeprecationHook(nil)
+	if v := GetWithFallbackKeys("default", "_TEST_ENV_UNSET", "_TEST_ENV_OLD"); v != "old" {
+		t.Fatalf("Expected %q without hook but got %q", "old", v)
+	}
+}
+
+func TestRequire(t *testing.T) {
+	t.Setenv("_TEST_ENV_SET", "value")
+	t.Setenv("_TEST_ENV_EMPTY", "")
+
+	if err := Require("_TEST_ENV_SET"); err != nil {
+		t.Fatalf("Expected no error but got %s", err)
+	}
+	if err := Require(); err != nil {
+		t.Fatalf("Expected no error but got %s", err)
+	}
+
+	err := Require("_TEST_ENV_EMPTY", "_TEST_ENV_SET", "_TEST_ENV_UNSET")
+	if err == nil {
+		t.Fatal("Expected an error for missing variables")
+	}
+	expected := "missing required environment variables: _TEST_ENV_EMPTY, _TEST_ENV_UNSET"
+	if err.Error() != expected {
+		t.Fatalf("Expected %q but got %q", expected, err)
+	}
+}
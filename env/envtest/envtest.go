@@ -0,0 +1,44 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package envtest sets environment variables for the duration of a test.
+package envtest
+
+import (
+	"os"
+	"testing"
+)
+
+// Set sets the environment variable key to value for the duration of
+// the test t. The previous value is restored when the test and its
+// subtests complete, and the variable is unset again if it wasn't set
+// before. Like t.Setenv, it changes the environment of the whole
+// process, so it must not be used in parallel tests.
+func Set(t testing.TB, key, value string) {
+	t.Helper()
+	prev, ok := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatalf("unable to set environment variable %s: %v", key, err)
+	}
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package envtest
+
+import (
+	"os"
+	"testing"
+
+	"github.com/minio/pkg/v3/env"
+)
+
+func TestSet(t *testing.T) {
+	os.Unsetenv("_TEST_ENV_UNSET")
+	t.Setenv("_TEST_ENV_SET", "previous")
+
+	t.Run("set", func(t *testing.T) {
+		Set(t, "_TEST_ENV_UNSET", "value")
+		Set(t, "_TEST_ENV_SET", "value")
+		for _, key := range []string{"_TEST_ENV_UNSET", "_TEST_ENV_SET"} {
+			if v := env.Get(key, ""); v != "value" {
+				t.Fatalf("Expected %s to be value but got %q", key, v)
+			}
+		}
+		// Setting the same variable twice restores the original value.
+		Set(t, "_TEST_ENV_SET", "")
+		if env.IsSet("_TEST_ENV_SET") {
+			t.Fatal("Expected _TEST_ENV_SET to be empty")
+		}
+	})
+
+	if _, ok := os.LookupEnv("_TEST_ENV_UNSET"); ok {
+		t.Fatal("Expected _TEST_ENV_UNSET to be unset after the test")
+	}
+	if v := env.Get("_TEST_ENV_SET", ""); v != "previous" {
+		t.Fatalf("Expected _TEST_ENV_SET to be restored to previous but got %q", v)
+	}
+}
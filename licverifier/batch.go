@@ -0,0 +1,70 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"context"
+	"sync"
+
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// VerifyBatch verifies licenses using at most concurrency goroutines and
+// returns the license info and error of licenses[i] at index i of the
+// returned slices. A concurrency less than one is treated as one. Once ctx
+// is canceled, the licenses not verified yet fail with the context error.
+// VerifyBatch returns after all goroutines it started have exited.
+func (lv *LicenseVerifier) VerifyBatch(ctx context.Context, licenses []string, concurrency int, options ...jwt.ParseOption) ([]LicenseInfo, []error) {
+	infos := make([]LicenseInfo, len(licenses))
+	errs := make([]error, len(licenses))
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > len(licenses) {
+		concurrency = len(licenses)
+	}
+
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				infos[i], errs[i] = lv.VerifyContext(ctx, licenses[i], options...)
+			}
+		}()
+	}
+
+	i := 0
+feed:
+	for ; i < len(licenses); i++ {
+		select {
+		case indices <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indices)
+	wg.Wait()
+
+	for ; i < len(licenses); i++ {
+		errs[i] = ctx.Err()
+	}
+	return infos, errs
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+func TestVerifyBatch(t *testing.T) {
+	priv := newTestECKey(t)
+	lv, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+
+	testCases := []struct {
+		lic         string
+		accountID   int64
+		expectedErr error
+	}{
+		{signTestLicense(t, jwa.ES384, priv, map[string]interface{}{accountID: 1}), 1, nil},
+		{signTestLicense(t, jwa.ES384, priv, map[string]interface{}{jwt.ExpirationKey: time.Now().Add(-time.Hour)}), 0, ErrLicenseExpired},
+		{"not-a-license", 0, ErrInvalidSignature},
+		{signTestLicense(t, jwa.ES384, priv, map[string]interface{}{accountID: 2}), 2, nil},
+		{signTestLicense(t, jwa.ES384, priv, map[string]interface{}{organization: nil}), 0, ErrMalformedClaims},
+		{signTestLicense(t, jwa.ES384, priv, map[string]interface{}{accountID: 3}), 3, nil},
+	}
+	licenses := make([]string, len(testCases))
+	for i, tc := range testCases {
+		licenses[i] = tc.lic
+	}
+
+	for _, concurrency := range []int{0, 1, 4, 100} {
+		infos, errs := lv.VerifyBatch(context.Background(), licenses, concurrency)
+		if len(infos) != len(licenses) || len(errs) != len(licenses) {
+			t.Fatalf("Expected %d results but got %d infos and %d errors", len(licenses), len(infos), len(errs))
+		}
+		for i, tc := range testCases {
+			if tc.expectedErr == nil && errs[i] != nil {
+				t.Fatalf("%d: Expected license to pass verification but failed with %s", i+1, errs[i])
+			}
+			if !errors.Is(errs[i], tc.expectedErr) {
+				t.Fatalf("%d: Expected error %v but got %v", i+1, tc.expectedErr, errs[i])
+			}
+			if infos[i].AccountID != tc.accountID {
+				t.Fatalf("%d: Expected account ID %d but got %d", i+1, tc.accountID, infos[i].AccountID)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, errs := lv.VerifyBatch(ctx, licenses, 2)
+	for i, err := range errs {
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("%d: Expected context canceled error, got %v", i+1, err)
+		}
+	}
+
+	if infos, errs := lv.VerifyBatch(context.Background(), nil, 4); len(infos) != 0 || len(errs) != 0 {
+		t.Fatalf("Expected no results for an empty batch")
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// PEM block types of a license bundle.
+const (
+	bundleLicenseBlock   = "LICENSE KEY"
+	bundlePublicKeyBlock = "PUBLIC KEY"
+)
+
+// VerifyBundle verifies the license of an offline license bundle against the
+// public key shipped in the same bundle, and checks that it was issued for
+// the deployment deploymentID.
+//
+// A bundle is made of two PEM blocks, in any order: a "LICENSE KEY" block
+// holding the license token and a "PUBLIC KEY" block holding the PKIX public
+// key it was signed with:
+//
+//	-----BEGIN LICENSE KEY-----
+//	<base64 of the license token>
+//	-----END LICENSE KEY-----
+//	-----BEGIN PUBLIC KEY-----
+//	<base64 of the DER public key>
+//	-----END PUBLIC KEY-----
+//
+// As the key comes with the license, the bundle is only as trustworthy as the
+// channel it was received from. Like VerifyLicenseFile, the license info is
+// returned along with ErrInGracePeriod.
+func VerifyBundle(bundle []byte, deploymentID string, options ...jwt.ParseOption) (LicenseInfo, error) {
+	blocks := make(map[string]*pem.Block)
+	for rest := bundle; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			if len(bytes.TrimSpace(rest)) > 0 {
+				return LicenseInfo{}, errors.New("license bundle has trailing data that isn't a PEM block")
+			}
+			break
+		}
+		switch block.Type {
+		case bundleLicenseBlock, bundlePublicKeyBlock:
+		default:
+			return LicenseInfo{}, fmt.Errorf("license bundle has unexpected %s block", block.Type)
+		}
+		if _, ok := blocks[block.Type]; ok {
+			return LicenseInfo{}, fmt.Errorf("license bundle has more than one %s block", block.Type)
+		}
+		blocks[block.Type] = block
+	}
+	for _, typ := range []string{bundleLicenseBlock, bundlePublicKeyBlock} {
+		if _, ok := blocks[typ]; !ok {
+			return LicenseInfo{}, fmt.Errorf("license bundle has no %s block", typ)
+		}
+	}
+
+	lv, err := NewLicenseVerifier(pem.EncodeToMemory(blocks[bundlePublicKeyBlock]))
+	if err != nil {
+		return LicenseInfo{}, fmt.Errorf("invalid public key in license bundle: %w", err)
+	}
+	return lv.verifyDeployment(string(bytes.TrimSpace(blocks[bundleLicenseBlock].Bytes)), deploymentID, options)
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+func TestVerifyBundle(t *testing.T) {
+	priv := newTestECKey(t)
+	pubPEM := string(publicKeyPEM(t, &priv.PublicKey))
+	licensePEM := func(lic string) string {
+		return string(pem.EncodeToMemory(&pem.Block{Type: "LICENSE KEY", Bytes: []byte(lic)}))
+	}
+	lic := licensePEM(signTestLicense(t, jwa.ES384, priv, map[string]interface{}{deploymentID: "abc123"}))
+
+	for i, bundle := range []string{lic + pubPEM, pubPEM + lic, "\n" + lic + "\n" + pubPEM + "\n"} {
+		licInfo, err := VerifyBundle([]byte(bundle), "abc123")
+		if err != nil {
+			t.Fatalf("%d: Expected bundle to pass verification but failed with %s", i+1, err)
+		}
+		if licInfo.DeploymentID != "abc123" || licInfo.Organization != "Example Inc." {
+			t.Fatalf("%d: Unexpected license info %v", i+1, licInfo)
+		}
+	}
+
+	testCases := []struct {
+		bundle      string
+		depID       string
+		expectedErr error
+		errMsg      string
+	}{
+		{lic + pubPEM, "def456", ErrDeploymentMismatch, ""},
+		{licensePEM(signTestLicense(t, jwa.ES384, priv, map[string]interface{}{deploymentID: "abc123", jwt.ExpirationKey: time.Now().Add(-time.Hour)})) + pubPEM, "abc123", ErrLicenseExpired, ""},
+		{licensePEM(signTestLicense(t, jwa.ES384, newTestECKey(t), map[string]interface{}{deploymentID: "abc123"})) + pubPEM, "abc123", ErrInvalidSignature, ""},
+		{pubPEM, "abc123", nil, "no LICENSE KEY block"},
+		{lic, "abc123", nil, "no PUBLIC KEY block"},
+		{"", "abc123", nil, "no LICENSE KEY block"},
+		{lic + lic + pubPEM, "abc123", nil, "more than one LICENSE KEY block"},
+		{lic + pubPEM + "-----BEGIN CERTIFICATE-----\nAAAA\n-----END CERTIFICATE-----\n", "abc123", nil, "unexpected CERTIFICATE block"},
+		{lic + pubPEM + "garbage", "abc123", nil, "trailing data"},
+		{lic + "-----BEGIN PUBLIC KEY-----\nAAAA\n-----END PUBLIC KEY-----\n", "abc123", nil, "invalid public key"},
+	}
+	for i, tc := range testCases {
+		licInfo, err := VerifyBundle([]byte(tc.bundle), tc.depID)
+		if err == nil {
+			t.Fatalf("%d: Expected bundle to fail verification", i+1)
+		}
+		if tc.expectedErr != nil && !errors.Is(err, tc.expectedErr) {
+			t.Fatalf("%d: Expected error %v but got %v", i+1, tc.expectedErr, err)
+		}
+		if !strings.Contains(err.Error(), tc.errMsg) {
+			t.Fatalf("%d: Expected error to mention %q, got %s", i+1, tc.errMsg, err)
+		}
+		if licInfo.Organization != "" {
+			t.Fatalf("%d: Expected zero license info but got %v", i+1, licInfo)
+		}
+	}
+
+	// Licenses in their grace period are returned with ErrInGracePeriod.
+	expired := licensePEM(signTestLicense(t, jwa.ES384, priv, map[string]interface{}{deploymentID: "abc123", jwt.ExpirationKey: time.Now().Add(-time.Hour)}))
+	licInfo, err := VerifyBundle([]byte(expired+pubPEM), "abc123", WithGracePeriod(2*time.Hour))
+	if !errors.Is(err, ErrInGracePeriod) {
+		t.Fatalf("Expected license in grace period error, got %v", err)
+	}
+	if licInfo.DeploymentID != "abc123" || licInfo.Organization != "Example Inc." {
+		t.Fatalf("Expected license info along with the grace period error, got %v", licInfo)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// minCachePurge is the number of entries a VerifyCache holds before expired
+// entries are purged.
+const minCachePurge = 64
+
+// VerifyCache caches the outcome of license signature verification, keyed by
+// the SHA-256 of the license string, to speed up repeated verifications of
+// the same license. The cache relies on SHA-256 being collision resistant:
+// two licenses with the same hash would share an entry, which is deemed
+// impossible in practice.
+// Entries are kept until the license expires or for the maximum TTL of the
+// cache, whichever is sooner. A cached license is still validated on every
+// Verify call, so a license that expired since it was cached is rejected.
+// Entries are only used by the verifier, with the same trusted keys, that
+// created them. It is safe for concurrent use.
+type VerifyCache struct {
+	maxTTL  time.Duration
+	rawKeys bool // key entries by license rather than by its hash
+
+	mu        sync.Mutex
+	entries   map[string]cacheEntry
+	nextPurge int
+}
+
+type cacheEntry struct {
+	keys      jwk.Set
+	token     jwt.Token
+	expiresAt time.Time
+}
+
+// CacheOption configures a VerifyCache.
+type CacheOption func(*VerifyCache)
+
+// WithRawCacheKeys keys the cache entries by the license string itself rather
+// than by its SHA-256, which saves hashing licenses at the cost of keeping
+// them in memory for the lifetime of their entries.
+func WithRawCacheKeys() CacheOption {
+	return func(c *VerifyCache) {
+		c.rawKeys = true
+	}
+}
+
+// NewVerifyCache returns an empty cache whose entries live at most maxTTL.
+// A zero maxTTL keeps entries until their license expires.
+func NewVerifyCache(maxTTL time.Duration, opts ...CacheOption) *VerifyCache {
+	c := &VerifyCache{
+		maxTTL:    maxTTL,
+		entries:   make(map[string]cacheEntry),
+		nextPurge: minCachePurge,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// key returns the key of the entry of license.
+func (c *VerifyCache) key(license string) string {
+	if c.rawKeys {
+		return license
+	}
+	sum := sha256.Sum256([]byte(license))
+	return string(sum[:])
+}
+
+// WithCache makes Verify look up and store verified licenses in c.
+func WithCache(c *VerifyCache) jwt.ParseOption {
+	return newVerifyOption(func(o *verifyOptions) {
+		o.cache = c
+	})
+}
+
+// get returns the token cached for license verified with keys.
+func (c *VerifyCache) get(license string, keys jwk.Set, now time.Time) (jwt.Token, bool) {
+	key := c.key(license)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !now.Before(e.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	if e.keys != keys {
+		return nil, false
+	}
+	return e.token, true
+}
+
+// put caches the token of license verified with keys.
+func (c *VerifyCache) put(license string, keys jwk.Set, token jwt.Token, now time.Time) {
+	expiresAt := token.Expiration()
+	if c.maxTTL > 0 && (expiresAt.IsZero() || now.Add(c.maxTTL).Before(expiresAt)) {
+		expiresAt = now.Add(c.maxTTL)
+	}
+	if expiresAt.IsZero() || !now.Before(expiresAt) {
+		return
+	}
+
+	key := c.key(license)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= c.nextPurge {
+		for k, e := range c.entries {
+			if !now.Before(e.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		c.nextPurge = 2 * len(c.entries)
+		if c.nextPurge < minCachePurge {
+			c.nextPurge = minCachePurge
+		}
+	}
+	c.entries[key] = cacheEntry{keys: keys, token: token, expiresAt: expiresAt}
+}
+
+// len returns the number of cached entries.
+func (c *VerifyCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
@@ -0,0 +1,216 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"crypto/sha256"
+	"errors"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+func TestVerifyCache(t *testing.T) {
+	priv := newTestECKey(t)
+	lv, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	exp := now.Add(time.Hour)
+	lic := signTestLicense(t, jwa.ES384, priv, map[string]interface{}{
+		jwt.IssuedAtKey:   now.Add(-time.Hour),
+		jwt.ExpirationKey: exp,
+	})
+	clock := func(now time.Time) jwt.ParseOption {
+		return WithClock(jwt.ClockFunc(func() time.Time { return now }))
+	}
+
+	cache := NewVerifyCache(0)
+	for i := 0; i < 2; i++ {
+		licInfo, err := lv.Verify(lic, WithCache(cache), clock(now))
+		if err != nil {
+			t.Fatalf("%d: Expected license to pass verification but failed with %s", i+1, err)
+		}
+		if licInfo.Organization != "Example Inc." || licInfo.LicenseToken != lic {
+			t.Fatalf("%d: Unexpected license info %v", i+1, licInfo)
+		}
+		if cache.len() != 1 {
+			t.Fatalf("%d: Expected 1 cached entry but got %d", i+1, cache.len())
+		}
+	}
+
+	// A cache hit is still validated against the options of the call.
+	if _, err = lv.Verify(lic, WithCache(cache), clock(now), WithExpectedIssuer("subnet")); !errors.Is(err, ErrInvalidIssuer) {
+		t.Fatalf("Expected cached license to fail issuer check, got %v", err)
+	}
+	if _, err = lv.Verify(lic, WithCache(cache), clock(exp)); !errors.Is(err, ErrLicenseExpired) {
+		t.Fatalf("Expected cached license to be expired, got %v", err)
+	}
+	if cache.len() != 0 {
+		t.Fatalf("Expected expired entry to be evicted, got %d entries", cache.len())
+	}
+
+	// Entries of another verifier are not used.
+	other, err := NewLicenseVerifier(publicKeyPEM(t, &newTestECKey(t).PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	if _, err = lv.Verify(lic, WithCache(cache), clock(now)); err != nil {
+		t.Fatalf("Expected license to pass verification but failed with %s", err)
+	}
+	if _, err = other.Verify(lic, WithCache(cache), clock(now)); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("Expected cached license to fail with another verifier, got %v", err)
+	}
+}
+
+func TestVerifyCacheMaxTTL(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	token := jwt.New()
+	token.Set(jwt.ExpirationKey, now.Add(time.Hour))
+
+	cache := NewVerifyCache(time.Minute)
+	cache.put("lic", nil, token, now)
+	if _, ok := cache.get("lic", nil, now.Add(59*time.Second)); !ok {
+		t.Fatal("Expected entry to be cached before max TTL")
+	}
+	if _, ok := cache.get("lic", nil, now.Add(time.Minute)); ok {
+		t.Fatal("Expected entry to be evicted after max TTL")
+	}
+
+	cache = NewVerifyCache(2 * time.Hour)
+	cache.put("lic", nil, token, now)
+	if _, ok := cache.get("lic", nil, now.Add(time.Hour)); ok {
+		t.Fatal("Expected entry to be evicted once the license expires")
+	}
+
+	// Licenses without expiry are only cached with a max TTL.
+	cache = NewVerifyCache(0)
+	cache.put("lic", nil, jwt.New(), now)
+	if cache.len() != 0 {
+		t.Fatal("Expected license without expiry not to be cached")
+	}
+}
+
+func TestVerifyCacheKeys(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	tokenA, tokenB := jwt.New(), jwt.New()
+	tokenA.Set(jwt.ExpirationKey, now.Add(time.Hour))
+	tokenB.Set(jwt.ExpirationKey, now.Add(time.Hour))
+	licA := strings.Repeat("a", 16<<10)
+	licB := licA[:len(licA)-1] + "b"
+
+	hashed := NewVerifyCache(0)
+	for i, cache := range []*VerifyCache{hashed, NewVerifyCache(0, WithRawCacheKeys())} {
+		cache.put(licA, nil, tokenA, now)
+		cache.put(licB, nil, tokenB, now)
+		if cache.len() != 2 {
+			t.Fatalf("%d: Expected 2 cached entries but got %d", i+1, cache.len())
+		}
+		if token, ok := cache.get(licA, nil, now); !ok || token != tokenA {
+			t.Fatalf("%d: Expected token of the first license", i+1)
+		}
+		if token, ok := cache.get(licB, nil, now); !ok || token != tokenB {
+			t.Fatalf("%d: Expected token of the second license", i+1)
+		}
+		if _, ok := cache.get(licA+"c", nil, now); ok {
+			t.Fatalf("%d: Expected no entry for another license", i+1)
+		}
+	}
+	for key := range hashed.entries {
+		if len(key) != sha256.Size {
+			t.Fatalf("Expected key of %d bytes but got %d", sha256.Size, len(key))
+		}
+	}
+}
+
+func TestVerifyCacheConcurrent(t *testing.T) {
+	priv := newTestECKey(t)
+	lv, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	var lics []string
+	for i := 0; i < 4; i++ {
+		lics = append(lics, signTestLicense(t, jwa.ES384, priv, map[string]interface{}{accountID: i}))
+	}
+
+	cache := NewVerifyCache(time.Minute)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				lic := lics[(i+j)%len(lics)]
+				licInfo, err := lv.Verify(lic, WithCache(cache))
+				if err != nil {
+					t.Errorf("Expected license to pass verification but failed with %s", err)
+					return
+				}
+				if licInfo.AccountID != int64((i+j)%len(lics)) {
+					t.Errorf("Unexpected account ID %d", licInfo.AccountID)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// BenchmarkVerifyCacheKeys compares the memory held by a cache of large
+// licenses keyed by their hash and by the license strings.
+func BenchmarkVerifyCacheKeys(b *testing.B) {
+	const entries = 1000
+	now := time.Now()
+	token := jwt.New()
+	token.Set(jwt.ExpirationKey, now.Add(time.Hour))
+	large := strings.Repeat("x", 8<<10)
+
+	for _, bc := range []struct {
+		name string
+		opts []CacheOption
+	}{
+		{"Hashed", nil},
+		{"Raw", []CacheOption{WithRawCacheKeys()}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			var held uint64
+			for i := 0; i < b.N; i++ {
+				var before, after runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+				cache := NewVerifyCache(0, bc.opts...)
+				for j := 0; j < entries; j++ {
+					cache.put(large+strconv.Itoa(j), nil, token, now)
+				}
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				held += after.HeapAlloc - before.HeapAlloc
+				runtime.KeepAlive(cache)
+			}
+			b.ReportMetric(float64(held)/float64(b.N*entries), "B/entry")
+		})
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// bytesPerTB is the number of bytes in a TB, the unit of Capacity.
+const bytesPerTB = 1_000_000_000_000
+
+// bytesPerTiB is the number of bytes in a TiB.
+const bytesPerTiB = 1 << 40
+
+// Capacity is a storage capacity in decimal TB, as carried by the cap claim.
+type Capacity int64
+
+// TB returns the capacity in TB.
+func (c Capacity) TB() int64 {
+	return int64(c)
+}
+
+// Bytes returns the capacity in bytes, or math.MaxInt64 if it doesn't fit in
+// an int64.
+func (c Capacity) Bytes() int64 {
+	if c > math.MaxInt64/bytesPerTB {
+		return math.MaxInt64
+	}
+	return int64(c) * bytesPerTB
+}
+
+// TiB returns the capacity in TiB.
+func (c Capacity) TiB() float64 {
+	return float64(c) * bytesPerTB / bytesPerTiB
+}
+
+// capacityUnits are the unit suffixes accepted in capacity strings, with the
+// number of bytes they stand for.
+var capacityUnits = map[string]float64{
+	"B":   1,
+	"KB":  1e3,
+	"MB":  1e6,
+	"GB":  1e9,
+	"TB":  bytesPerTB,
+	"PB":  1e15,
+	"EB":  1e18,
+	"KiB": 1 << 10,
+	"MiB": 1 << 20,
+	"GiB": 1 << 30,
+	"TiB": bytesPerTiB,
+	"PiB": 1 << 50,
+	"EiB": 1 << 60,
+}
+
+// parseCapacity parses a capacity with a unit suffix, e.g. 500TB or 512GiB,
+// and returns it rounded to the nearest TB along with its exact number of
+// bytes, or math.MaxInt64 if it doesn't fit in an int64. Capacities below
+// half a TB are rounded up to 1 TB rather than to zero, which stands for no
+// limit.
+func parseCapacity(s string) (Capacity, int64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i <= 0 {
+		return 0, 0, fmt.Errorf("capacity %q must be a number followed by a unit", s)
+	}
+	unit, ok := capacityUnits[strings.TrimSpace(s[i:])]
+	if !ok {
+		return 0, 0, fmt.Errorf("capacity %q has an unknown unit", s)
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("capacity %q must be a number followed by a unit", s)
+	}
+	bytes := math.Round(n * unit)
+	tb := math.Round(bytes / bytesPerTB)
+	switch {
+	case tb >= math.MaxInt64:
+		return 0, 0, fmt.Errorf("capacity %q is too large", s)
+	case tb == 0 && bytes > 0:
+		tb = 1
+	}
+	// Like Capacity.Bytes, the number of bytes saturates at math.MaxInt64.
+	if bytes >= math.MaxInt64 {
+		return Capacity(tb), math.MaxInt64, nil
+	}
+	return Capacity(tb), int64(bytes), nil
+}
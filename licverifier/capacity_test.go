@@ -0,0 +1,142 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/jwa"
+)
+
+func TestCapacity(t *testing.T) {
+	testCases := []struct {
+		capacity Capacity
+		tb       int64
+		bytes    int64
+		tib      float64
+	}{
+		{0, 0, 0, 0},
+		{1, 1, 1_000_000_000_000, 0.9094947017729282},
+		{1100, 1100, 1_100_000_000_000_000, 1000.4441719502211},
+		{math.MaxInt64, math.MaxInt64, math.MaxInt64, float64(math.MaxInt64) * 1e12 / (1 << 40)},
+	}
+	for i, tc := range testCases {
+		if got := tc.capacity.TB(); got != tc.tb {
+			t.Errorf("%d: Expected %d TB but got %d", i+1, tc.tb, got)
+		}
+		if got := tc.capacity.Bytes(); got != tc.bytes {
+			t.Errorf("%d: Expected %d bytes but got %d", i+1, tc.bytes, got)
+		}
+		if got := tc.capacity.TiB(); got != tc.tib {
+			t.Errorf("%d: Expected %v TiB but got %v", i+1, tc.tib, got)
+		}
+	}
+}
+
+func TestLicenseInfoCap(t *testing.T) {
+	priv := newTestECKey(t)
+	lv, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	licInfo, err := lv.Verify(signTestLicense(t, jwa.ES384, priv, map[string]interface{}{capacity: 250}))
+	if err != nil {
+		t.Fatalf("Expected license to pass verification but failed with %s", err)
+	}
+	if licInfo.StorageCapacity != 250 || licInfo.Cap.TB() != licInfo.StorageCapacity {
+		t.Fatalf("Expected capacity of 250 TB, got %d and %d", licInfo.StorageCapacity, licInfo.Cap)
+	}
+	if licInfo.Cap.Bytes() != licInfo.CapacityBytes() {
+		t.Fatalf("Expected %d bytes but got %d", licInfo.CapacityBytes(), licInfo.Cap.Bytes())
+	}
+}
+
+func TestCapacityClaimWithUnit(t *testing.T) {
+	priv := newTestECKey(t)
+	lv, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+
+	testCases := []struct {
+		capacity      interface{}
+		expectedTB    int64
+		expectedBytes int64
+		expectedErr   error
+	}{
+		{500, 500, 500e12, nil},
+		{"500TB", 500, 500e12, nil},
+		{"512GiB", 1, 512 << 30, nil},
+		{"2048GiB", 2, 2048 << 30, nil},
+		{"1.5PB", 1500, 1500e12, nil},
+		{"100TiB", 110, 100 << 40, nil},
+		{"1000000000000B", 1, 1e12, nil},
+		{"1GB", 1, 1e9, nil},
+		{"0TB", 0, 0, nil},
+		{" 500 TB ", 500, 500e12, nil},
+		{"10EB", 10_000_000, math.MaxInt64, nil},
+		{"500XB", 0, 0, ErrMalformedClaims},
+		{"500tb", 0, 0, ErrMalformedClaims},
+		{"500", 0, 0, ErrMalformedClaims},
+		{"TB", 0, 0, ErrMalformedClaims},
+		{"-5TB", 0, 0, ErrMalformedClaims},
+		{"1.2.3TB", 0, 0, ErrMalformedClaims},
+		{"10000000000000EB", 0, 0, ErrMalformedClaims},
+	}
+	for i, tc := range testCases {
+		licInfo, err := lv.Verify(signTestLicense(t, jwa.ES384, priv, map[string]interface{}{capacity: tc.capacity}))
+		if !errors.Is(err, tc.expectedErr) {
+			t.Fatalf("%d: Expected error %v but got %v", i+1, tc.expectedErr, err)
+		}
+		if licInfo.StorageCapacity != tc.expectedTB || licInfo.Cap.TB() != tc.expectedTB {
+			t.Fatalf("%d: Expected capacity of %d TB, got %d and %d", i+1, tc.expectedTB, licInfo.StorageCapacity, licInfo.Cap)
+		}
+		if got := licInfo.CapacityBytes(); got != tc.expectedBytes {
+			t.Fatalf("%d: Expected capacity of %d bytes, got %d", i+1, tc.expectedBytes, got)
+		}
+	}
+
+	// Usage is checked against the exact capacity, not the rounded one.
+	lic := signTestLicense(t, jwa.ES384, priv, map[string]interface{}{capacity: "512GiB"})
+	if _, err = lv.Verify(lic, WithUsage(512<<30)); err != nil {
+		t.Fatalf("Expected usage within capacity to pass verification but failed with %s", err)
+	}
+	if _, err = lv.Verify(lic, WithUsage(900e9)); !errors.Is(err, ErrCapacityExceeded) {
+		t.Fatalf("Expected error %v but got %v", ErrCapacityExceeded, err)
+	}
+
+	// The exact capacity survives JSON encoding.
+	licInfo, err := lv.Verify(lic)
+	if err != nil {
+		t.Fatalf("Expected license to pass verification but failed with %s", err)
+	}
+	data, err := json.Marshal(licInfo)
+	if err != nil {
+		t.Fatalf("Failed to marshal license info: %s", err)
+	}
+	var decoded LicenseInfo
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal license info: %s", err)
+	}
+	if got := decoded.CapacityBytes(); got != 512<<30 {
+		t.Fatalf("Expected capacity of %d bytes after JSON round trip, got %d", 512<<30, got)
+	}
+}
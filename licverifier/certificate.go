@@ -0,0 +1,96 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// licenseExtensionOID is the object identifier of the X.509 extension read by
+// VerifyFromCertificate by default.
+var licenseExtensionOID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 59706, 1, 1}
+
+// LicenseExtensionOID returns the object identifier of the X.509 extension
+// carrying a license key in a certificate read by VerifyFromCertificate by
+// default, 1.3.6.1.4.1.59706.1.1. The extension value is the license key as
+// a DER encoded UTF8String.
+//
+// The enterprise number 59706 of this OID isn't one registered to MinIO in
+// the IANA Private Enterprise Numbers registry: certificate issuers owning a
+// private enterprise number should set an OID under their own with
+// WithCertificateExtensionOID.
+func LicenseExtensionOID() asn1.ObjectIdentifier {
+	return append(asn1.ObjectIdentifier(nil), licenseExtensionOID...)
+}
+
+// WithCertificateExtensionOID makes VerifyFromCertificate read the license
+// key from the extension oid rather than the one of LicenseExtensionOID.
+func WithCertificateExtensionOID(oid asn1.ObjectIdentifier) jwt.ParseOption {
+	oid = append(asn1.ObjectIdentifier(nil), oid...)
+	return newVerifyOption(func(o *verifyOptions) {
+		o.certExtensionOID = oid
+	})
+}
+
+// VerifyFromCertificate verifies the license key carried by the extension
+// LicenseExtensionOID, or the one set with WithCertificateExtensionOID, of
+// cert, e.g. a TLS client certificate, and checks that it was issued for the
+// deployment deploymentID like VerifyLicenseFile. The license is trusted for its own signature: the certificate itself isn't
+// verified, which is left to the TLS handshake.
+func (lv *LicenseVerifier) VerifyFromCertificate(cert *x509.Certificate, deploymentID string, options ...jwt.ParseOption) (LicenseInfo, error) {
+	vo, _ := splitOptions(options)
+	oid := vo.certExtensionOID
+	if oid == nil {
+		oid = licenseExtensionOID
+	}
+	lic, err := certificateLicense(cert, oid)
+	if err != nil {
+		return LicenseInfo{}, err
+	}
+	return lv.verifyDeployment(lic, deploymentID, options)
+}
+
+// certificateLicense returns the license key in the extension oid of cert.
+func certificateLicense(cert *x509.Certificate, oid asn1.ObjectIdentifier) (string, error) {
+	if cert == nil {
+		return "", errors.New("certificate is nil")
+	}
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oid) {
+			continue
+		}
+		var lic string
+		rest, err := asn1.Unmarshal(ext.Value, &lic)
+		if err != nil {
+			return "", fmt.Errorf("invalid license extension %s: %w", oid, err)
+		}
+		if len(rest) > 0 {
+			return "", fmt.Errorf("invalid license extension %s: trailing data", oid)
+		}
+		if lic == "" {
+			return "", fmt.Errorf("license extension %s is empty", oid)
+		}
+		return lic, nil
+	}
+	return "", fmt.Errorf("certificate %q has no license extension %s", cert.Subject.CommonName, oid)
+}
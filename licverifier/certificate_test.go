@@ -0,0 +1,138 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// licenseCert returns a self-signed client certificate carrying the given
+// extensions.
+func licenseCert(t *testing.T, exts ...pkix.Extension) *x509.Certificate {
+	t.Helper()
+	priv := newTestECKey(t)
+	tmpl := &x509.Certificate{
+		SerialNumber:    big.NewInt(1),
+		Subject:         pkix.Name{CommonName: "client"},
+		NotBefore:       time.Now().Add(-time.Hour),
+		NotAfter:        time.Now().Add(time.Hour),
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		ExtraExtensions: exts,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %s", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// licenseExtension returns the license extension carrying value as a
+// UTF8String.
+func licenseExtension(t *testing.T, value string) pkix.Extension {
+	t.Helper()
+	der, err := asn1.MarshalWithParams(value, "utf8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkix.Extension{Id: LicenseExtensionOID(), Value: der}
+}
+
+func TestVerifyFromCertificate(t *testing.T) {
+	priv := newTestECKey(t)
+	lv, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	lic := signTestLicense(t, jwa.ES384, priv, map[string]interface{}{deploymentID: "abc123"})
+	other := pkix.Extension{Id: asn1.ObjectIdentifier{1, 2, 3, 4}, Value: []byte{0x05, 0x00}}
+
+	testCases := []struct {
+		cert        *x509.Certificate
+		depID       string
+		expectedErr error
+		errContains string
+	}{
+		{licenseCert(t, other, licenseExtension(t, lic)), "abc123", nil, ""},
+		{licenseCert(t, licenseExtension(t, lic)), "def456", ErrDeploymentMismatch, ""},
+		{licenseCert(t, licenseExtension(t, signTestLicense(t, jwa.ES384, newTestECKey(t), nil))), "abc123", ErrInvalidSignature, ""},
+		{licenseCert(t, other), "abc123", nil, "no license extension"},
+		{licenseCert(t, licenseExtension(t, "")), "abc123", nil, "is empty"},
+		{licenseCert(t, pkix.Extension{Id: LicenseExtensionOID(), Value: []byte(lic)}), "abc123", nil, "invalid license extension"},
+		{nil, "abc123", nil, "certificate is nil"},
+	}
+	for i, tc := range testCases {
+		li, err := lv.VerifyFromCertificate(tc.cert, tc.depID)
+		switch {
+		case tc.expectedErr != nil:
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("%d: Expected error %v but got %v", i+1, tc.expectedErr, err)
+			}
+		case tc.errContains != "":
+			if err == nil || !strings.Contains(err.Error(), tc.errContains) {
+				t.Fatalf("%d: Expected error containing %q but got %v", i+1, tc.errContains, err)
+			}
+		default:
+			if err != nil {
+				t.Fatalf("%d: Expected license to pass verification but failed with %s", i+1, err)
+			}
+			if li.LicenseToken != lic {
+				t.Fatalf("%d: Expected license token %q but got %q", i+1, lic, li.LicenseToken)
+			}
+		}
+	}
+
+	// Licenses in their grace period are returned with ErrInGracePeriod.
+	expired := signTestLicense(t, jwa.ES384, priv, map[string]interface{}{deploymentID: "abc123", jwt.ExpirationKey: time.Now().Add(-time.Hour)})
+	li, err := lv.VerifyFromCertificate(licenseCert(t, licenseExtension(t, expired)), "abc123", WithGracePeriod(2*time.Hour))
+	if !errors.Is(err, ErrInGracePeriod) {
+		t.Fatalf("Expected license in grace period error, got %v", err)
+	}
+	if li.LicenseToken != expired {
+		t.Fatalf("Expected license info along with the grace period error, got %v", li)
+	}
+	// The extension is read from the OID set by WithCertificateExtensionOID.
+	oid := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1}
+	ext := licenseExtension(t, lic)
+	ext.Id = oid
+	cert := licenseCert(t, ext)
+	if li, err = lv.VerifyFromCertificate(cert, "abc123", WithCertificateExtensionOID(oid)); err != nil || li.LicenseToken != lic {
+		t.Fatalf("Expected license of extension %s to pass verification, got %v: %v", oid, err, li)
+	}
+	if _, err = lv.VerifyFromCertificate(cert, "abc123"); err == nil || !strings.Contains(err.Error(), "no license extension") {
+		t.Fatalf("Expected no license extension error, got %v", err)
+	}
+	LicenseExtensionOID()[0] = 2
+	if _, err = lv.VerifyFromCertificate(licenseCert(t, licenseExtension(t, lic)), "abc123"); err != nil {
+		t.Fatalf("Expected the default OID not to be changed through LicenseExtensionOID, got %s", err)
+	}
+}
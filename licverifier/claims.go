@@ -0,0 +1,126 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// Claims holds all the claims of a license key, the registered JWT claims as
+// well as the license specific ones, with the values used by Verify: plan
+// defaults set by WithPlanDefaults are applied and strings are normalized by
+// WithNormalizeStrings.
+type Claims struct {
+	Issuer    string    // iss, issuer of the license
+	Subject   string    // sub, email of the license key requestor or customer UUID
+	Audience  []string  // aud, nil if not present
+	IssuedAt  time.Time // iat, zero if not present
+	NotBefore time.Time // nbf, zero if not present
+	ExpiresAt time.Time // exp
+	JwtID     string    // jti, empty if not present
+
+	LicenseID       string                 // lid, empty if not present
+	AccountID       int64                  // aid, Subnet account id
+	ParentAccountID int64                  // paid, Subnet account id of the reseller, 0 if not present
+	DeploymentID    string                 // did, cluster deployment ID
+	Organization    string                 // org, Subnet organization name
+	Capacity        Capacity               // cap, storage capacity rounded to the nearest TB
+	CapacityBytes   int64                  // cap, exact storage capacity in bytes
+	Plan            string                 // plan, Subnet plan
+	APIKey          string                 // apiKey, Subnet account API key
+	Trial           bool                   // trial, whether this is a trial license
+	MaxNodes        int64                  // nodes, maximum number of server nodes, 0 means unlimited
+	Features        map[string]bool        // features, features enabled or disabled by the license
+	Extra           map[string]interface{} // claims not mapped to any other field, nil if none
+
+	raw map[string]string // original values of the claims changed by WithNormalizeStrings
+}
+
+// VerifyClaims is like Verify but returns all the claims of the license.
+// Errors returned along with the claims, e.g. ErrInGracePeriod, are the same
+// as those Verify returns along with the license info.
+func (lv *LicenseVerifier) VerifyClaims(license string, options ...jwt.ParseOption) (Claims, error) {
+	token, _, err := lv.VerifyToken(license, options...)
+	if token == nil {
+		return Claims{}, err
+	}
+	vo, _ := splitOptions(options)
+	c, cerr := toClaims(token, vo)
+	if cerr != nil {
+		return Claims{}, cerr
+	}
+	return c, err
+}
+
+// toLicenseInfo extracts LicenseInfo from the claims of token. It returns an
+// error if any of the claim values are invalid.
+func toLicenseInfo(license string, token jwt.Token, vo verifyOptions) (LicenseInfo, error) {
+	c, err := toClaims(token, vo)
+	if err != nil {
+		return LicenseInfo{}, err
+	}
+	// LicenseInfo has no audience field, it is kept with the extra claims.
+	// c isn't returned, its extra claims can be updated in place.
+	if len(c.Audience) > 0 {
+		if c.Extra == nil {
+			c.Extra = reuseExtra(vo.extra)
+		}
+		c.Extra[jwt.AudienceKey] = c.Audience
+	}
+	return c.licenseInfo(license), nil
+}
+
+// licenseInfo returns the license info of the license key license carrying
+// the claims c, whose extra claims must include the audience if any.
+func (c Claims) licenseInfo(license string) LicenseInfo {
+	// Fall back to the standard jti claim when lid isn't set.
+	licID := c.LicenseID
+	if licID == "" {
+		licID = c.JwtID
+	}
+	var email string
+	if isEmail(c.Subject) {
+		email = c.Subject
+	}
+	return LicenseInfo{
+		LicenseToken:    license,
+		LicenseID:       licID,
+		Email:           email,
+		Subject:         c.Subject,
+		Organization:    c.Organization,
+		AccountID:       c.AccountID,
+		ParentAccountID: c.ParentAccountID,
+		DeploymentID:    c.DeploymentID,
+		StorageCapacity: c.Capacity.TB(),
+		StorageBytes:    storageBytes(c.Capacity, c.CapacityBytes),
+		Cap:             c.Capacity,
+		Plan:            c.Plan,
+		IssuedAt:        c.IssuedAt,
+		ExpiresAt:       c.ExpiresAt,
+		NotBefore:       c.NotBefore,
+		APIKey:          c.APIKey,
+		IsTrial:         c.Trial,
+		Issuer:          c.Issuer,
+		MaxNodes:        c.MaxNodes,
+		Features:        c.Features,
+		Extra:           c.Extra,
+		Raw:             c.raw,
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+func TestVerifyClaims(t *testing.T) {
+	priv := newTestECKey(t)
+	lv, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	now := time.Now().Truncate(time.Second)
+	lic := signTestLicense(t, jwa.ES384, priv, map[string]interface{}{
+		jwt.IssuerKey:     "subnet@min.io",
+		jwt.AudienceKey:   []string{"minio"},
+		jwt.IssuedAtKey:   now.Add(-time.Hour),
+		jwt.NotBeforeKey:  now.Add(-time.Minute),
+		jwt.ExpirationKey: now.Add(time.Hour),
+		jwt.JwtIDKey:      "jti-1",
+		accountID:         42,
+		parentAccID:       7,
+		deploymentID:      "abc123",
+		capacity:          "2048GiB",
+		trial:             true,
+		maxNodes:          16,
+		features:          map[string]bool{"replication": true},
+		"region":          "eu-west-1",
+	})
+
+	c, err := lv.VerifyClaims(lic)
+	if err != nil {
+		t.Fatalf("Expected license to pass verification but failed with %s", err)
+	}
+	expected := Claims{
+		Issuer:          "subnet@min.io",
+		Subject:         "jane@example.com",
+		Audience:        []string{"minio"},
+		IssuedAt:        now.Add(-time.Hour),
+		NotBefore:       now.Add(-time.Minute),
+		ExpiresAt:       now.Add(time.Hour),
+		JwtID:           "jti-1",
+		AccountID:       42,
+		ParentAccountID: 7,
+		DeploymentID:    "abc123",
+		Organization:    "Example Inc.",
+		Capacity:        2,
+		CapacityBytes:   2048 << 30,
+		Plan:            "STANDARD",
+		Trial:           true,
+		MaxNodes:        16,
+		Features:        map[string]bool{"replication": true},
+		Extra:           map[string]interface{}{"region": "eu-west-1"},
+	}
+	for _, tm := range []*time.Time{&c.IssuedAt, &c.NotBefore, &c.ExpiresAt} {
+		*tm = tm.Local()
+	}
+	if !reflect.DeepEqual(expected, c) {
+		t.Fatalf("Expected claims %#v but got %#v", expected, c)
+	}
+
+	// The license info is derived from the same claims.
+	li, err := lv.Verify(lic)
+	if err != nil {
+		t.Fatalf("Expected license to pass verification but failed with %s", err)
+	}
+	if li.LicenseID != "jti-1" || li.StorageCapacity != 2 || !reflect.DeepEqual(li.Extra, map[string]interface{}{"region": "eu-west-1", jwt.AudienceKey: []string{"minio"}}) {
+		t.Fatalf("Unexpected license info %#v", li)
+	}
+	if _, ok := c.Extra[jwt.AudienceKey]; ok {
+		t.Fatal("Expected audience not to be added to the extra claims")
+	}
+
+	expired := signTestLicense(t, jwa.ES384, priv, map[string]interface{}{jwt.ExpirationKey: now.Add(-time.Minute)})
+	if c, err = lv.VerifyClaims(expired); !errors.Is(err, ErrLicenseExpired) || c.AccountID != 0 {
+		t.Fatalf("Expected expired license to fail verification without claims, got %v and %#v", err, c)
+	}
+	if c, err = lv.VerifyClaims(expired, WithGracePeriod(time.Hour)); !errors.Is(err, ErrInGracePeriod) || c.AccountID != 1 {
+		t.Fatalf("Expected claims of a license in grace period, got %v and %#v", err, c)
+	}
+	if _, err = lv.VerifyClaims(signTestLicense(t, jwa.ES384, newTestECKey(t), nil)); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("Expected license signed by an untrusted key to fail verification, got %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// VerifyClusterLicenseWithVerifier verifies the license key lic with lv and
+// checks that it was issued for the deployment depID. It returns an error
+// wrapping ErrDeploymentMismatch if the deployment IDs differ.
+func VerifyClusterLicenseWithVerifier(lv *LicenseVerifier, lic, depID string, options ...jwt.ParseOption) error {
+	return lv.ValidFor(lic, depID, options...)
+}
+
+// ValidFor returns nil if the license is valid and was issued for the
+// deployment deploymentID. Otherwise, it returns the error returned by Verify
+// or an error wrapping ErrDeploymentMismatch. Licenses issued for
+// deploymentID in their grace period are reported like Verify does, with an
+// error wrapping ErrInGracePeriod, or ErrLicenseExpired for expired licenses
+// with WithIgnoreExpiry: use errors.Is to accept them.
+//
+// The deployment ID is checked on the claims of the license, the license
+// info is only built for the options checking it, e.g. WithAllowedPlans.
+func (lv *LicenseVerifier) ValidFor(license, deploymentID string, options ...jwt.ParseOption) error {
+	vo, options := splitOptions(options)
+	start := time.Now()
+	licID, err := lv.verifyDeploymentID(context.Background(), license, vo, options)
+	lv.observe(vo, time.Since(start), err)
+	if err != nil && !hasLicenseInfo(err) {
+		return err
+	}
+	if derr := checkDeploymentID(licID, deploymentID); derr != nil {
+		return derr
+	}
+	return err
+}
+
+// verifyDeploymentID verifies the license key license like verify and
+// returns the deployment ID it was issued for, along with the errors Verify
+// returns the license info with.
+func (lv *LicenseVerifier) verifyDeploymentID(ctx context.Context, license string, vo verifyOptions, options []jwt.ParseOption) (string, error) {
+	if vo.needsLicenseInfo() {
+		_, li, err := lv.verify(ctx, license, vo, options)
+		return li.DeploymentID, err
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	token, err := lv.parseCached(license, options, vo)
+	if err != nil {
+		return "", signatureError(err)
+	}
+	expiryErr, err := validateToken(ctx, license, token, vo, options)
+	if err != nil {
+		return "", err
+	}
+	c, err := toClaims(token, vo)
+	if err != nil {
+		return "", err
+	}
+	return c.DeploymentID, expiryErr
+}
+
+// VerifyLicenseFile verifies the license key stored in the file at path and
+// checks that it was issued for the deployment deploymentID. Leading and
+// trailing whitespace, such as the final newline, is removed from the file
+// content. Errors reading the file mention path, verification errors are
+// returned as is, along with the license info for those Verify returns it
+// with, e.g. ErrInGracePeriod.
+func (lv *LicenseVerifier) VerifyLicenseFile(path, deploymentID string, options ...jwt.ParseOption) (LicenseInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return LicenseInfo{}, fmt.Errorf("unable to read license from %s: %w", path, err)
+	}
+	lic := string(bytes.TrimSpace(data))
+	if lic == "" {
+		return LicenseInfo{}, fmt.Errorf("license file %s is empty", path)
+	}
+	return lv.verifyDeployment(lic, deploymentID, options)
+}
+
+// verifyDeployment verifies the license key lic like Verify and checks that
+// it was issued for the deployment deploymentID. The license info is
+// returned along with the errors Verify returns it with.
+func (lv *LicenseVerifier) verifyDeployment(lic, deploymentID string, options []jwt.ParseOption) (LicenseInfo, error) {
+	li, err := lv.Verify(lic, options...)
+	if err != nil && !hasLicenseInfo(err) {
+		return LicenseInfo{}, err
+	}
+	if derr := checkDeploymentID(li.DeploymentID, deploymentID); derr != nil {
+		return LicenseInfo{}, derr
+	}
+	return li, err
+}
+
+// hasLicenseInfo returns true if Verify returns the license info along with
+// err: ErrInGracePeriod, or ErrLicenseExpired with WithIgnoreExpiry.
+func hasLicenseInfo(err error) bool {
+	var verr *VerifyError
+	return errors.Is(err, ErrInGracePeriod) || errors.Is(err, ErrLicenseExpired) && !errors.As(err, &verr)
+}
+
+// VerifyClusterLicenseAny verifies the license key lic with lv and checks that
+// it was issued for one of the deployments depIDs. An empty depIDs doesn't
+// restrict the deployment, only the signature and claims are verified then.
+func VerifyClusterLicenseAny(lv *LicenseVerifier, lic string, depIDs []string, options ...jwt.ParseOption) error {
+	li, err := lv.Verify(lic, options...)
+	if err != nil {
+		return err
+	}
+	if len(depIDs) == 0 {
+		return nil
+	}
+	for _, depID := range depIDs {
+		if checkDeploymentID(li.DeploymentID, depID) == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: expected one of %v, got %s", ErrDeploymentMismatch, depIDs, li.DeploymentID)
+}
+
+// VerifyClusterLicensePattern verifies the license key lic with lv and
+// checks that its deployment ID matches pattern. A pattern ending with "*"
+// matches the deployment IDs starting with the rest of the pattern, e.g.
+// "tenant-acme-*" matches "tenant-acme-01"; any other pattern must be equal
+// to the deployment ID. An empty pattern doesn't restrict the deployment.
+func VerifyClusterLicensePattern(lv *LicenseVerifier, lic, pattern string, options ...jwt.ParseOption) error {
+	li, err := lv.Verify(lic, options...)
+	if err != nil {
+		return err
+	}
+	if pattern == "" {
+		return nil
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		if len(li.DeploymentID) < len(prefix) || subtle.ConstantTimeCompare([]byte(li.DeploymentID[:len(prefix)]), []byte(prefix)) != 1 {
+			return fmt.Errorf("%w: expected %s, got %s", ErrDeploymentMismatch, pattern, li.DeploymentID)
+		}
+		return nil
+	}
+	return checkDeploymentID(li.DeploymentID, pattern)
+}
+
+// checkDeploymentID returns an error wrapping ErrDeploymentMismatch if the
+// deployment ID licID of a license isn't depID. The IDs are compared in
+// constant time, so that the time taken doesn't reveal how much of the
+// expected ID a license matches.
+func checkDeploymentID(licID, depID string) error {
+	if subtle.ConstantTimeCompare([]byte(licID), []byte(depID)) != 1 {
+		return fmt.Errorf("%w: expected %s, got %s", ErrDeploymentMismatch, depID, licID)
+	}
+	return nil
+}
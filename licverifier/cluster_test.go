@@ -0,0 +1,268 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+func TestVerifyClusterLicenseWithVerifier(t *testing.T) {
+	priv := newTestECKey(t)
+	lv, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	lic := signTestLicense(t, jwa.ES384, priv, map[string]interface{}{deploymentID: "abc123"})
+	expired := signTestLicense(t, jwa.ES384, priv, map[string]interface{}{
+		deploymentID:      "abc123",
+		jwt.ExpirationKey: time.Now().Add(-time.Hour),
+	})
+
+	testCases := []struct {
+		lic         string
+		depID       string
+		expectedErr error
+	}{
+		{lic, "abc123", nil},
+		{lic, "def456", ErrDeploymentMismatch},
+		{lic, "", ErrDeploymentMismatch},
+		{expired, "abc123", ErrLicenseExpired},
+		{signTestLicense(t, jwa.ES384, newTestECKey(t), map[string]interface{}{deploymentID: "abc123"}), "abc123", ErrInvalidSignature},
+	}
+	for i, tc := range testCases {
+		err := VerifyClusterLicenseWithVerifier(lv, tc.lic, tc.depID)
+		if !errors.Is(err, tc.expectedErr) || (tc.expectedErr == nil && err != nil) {
+			t.Fatalf("%d: Expected error %v but got %v", i+1, tc.expectedErr, err)
+		}
+	}
+}
+
+func TestVerifyClusterLicenseAny(t *testing.T) {
+	priv := newTestECKey(t)
+	lv, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	lic := signTestLicense(t, jwa.ES384, priv, map[string]interface{}{deploymentID: "abc123"})
+
+	testCases := []struct {
+		lic         string
+		depIDs      []string
+		expectedErr error
+	}{
+		{lic, []string{"def456", "abc123"}, nil},
+		{lic, []string{"abc123"}, nil},
+		{lic, []string{"def456", "ghi789"}, ErrDeploymentMismatch},
+		{lic, nil, nil},
+		{lic, []string{}, nil},
+		{signTestLicense(t, jwa.ES384, newTestECKey(t), nil), nil, ErrInvalidSignature},
+	}
+	for i, tc := range testCases {
+		err := VerifyClusterLicenseAny(lv, tc.lic, tc.depIDs)
+		if !errors.Is(err, tc.expectedErr) || (tc.expectedErr == nil && err != nil) {
+			t.Fatalf("%d: Expected error %v but got %v", i+1, tc.expectedErr, err)
+		}
+	}
+}
+
+func TestValidFor(t *testing.T) {
+	priv := newTestECKey(t)
+	lv, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	lic := signTestLicense(t, jwa.ES384, priv, map[string]interface{}{
+		deploymentID:  "abc123",
+		jwt.IssuerKey: "subnet",
+	})
+
+	testCases := []struct {
+		depID       string
+		options     []jwt.ParseOption
+		expectedErr error
+	}{
+		{"abc123", nil, nil},
+		{"abc123", []jwt.ParseOption{WithExpectedIssuer("subnet")}, nil},
+		{"abc123", []jwt.ParseOption{WithExpectedIssuer("other")}, ErrInvalidIssuer},
+		{"def456", []jwt.ParseOption{WithExpectedIssuer("subnet")}, ErrDeploymentMismatch},
+		{"abc123", []jwt.ParseOption{WithRevocationList(NewRevocationList("abc123"))}, ErrLicenseRevoked},
+		{"abc123", []jwt.ParseOption{WithAllowedPlans(PlanEnterprise)}, ErrPlanNotAllowed},
+		{"def456", []jwt.ParseOption{WithAllowedPlans(PlanStandard)}, ErrDeploymentMismatch},
+		{"abc123", []jwt.ParseOption{WithRequiredClaims("lid")}, ErrMalformedClaims},
+	}
+	for i, tc := range testCases {
+		err := lv.ValidFor(lic, tc.depID, tc.options...)
+		if !errors.Is(err, tc.expectedErr) || (tc.expectedErr == nil && err != nil) {
+			t.Fatalf("%d: Expected error %v but got %v", i+1, tc.expectedErr, err)
+		}
+	}
+
+	// Licenses in their grace period are reported with ErrInGracePeriod,
+	// unless issued for another deployment.
+	expired := signTestLicense(t, jwa.ES384, priv, map[string]interface{}{deploymentID: "abc123", jwt.ExpirationKey: time.Now().Add(-time.Hour)})
+	graceCases := []struct {
+		depID       string
+		options     []jwt.ParseOption
+		expectedErr error
+	}{
+		{"abc123", []jwt.ParseOption{WithGracePeriod(2 * time.Hour)}, ErrInGracePeriod},
+		{"abc123", []jwt.ParseOption{WithGracePeriod(2 * time.Hour), WithAllowedPlans(PlanStandard)}, ErrInGracePeriod},
+		{"abc123", []jwt.ParseOption{WithIgnoreExpiry()}, ErrLicenseExpired},
+		{"def456", []jwt.ParseOption{WithGracePeriod(2 * time.Hour)}, ErrDeploymentMismatch},
+		{"abc123", nil, ErrLicenseExpired},
+	}
+	for i, tc := range graceCases {
+		if err := lv.ValidFor(expired, tc.depID, tc.options...); !errors.Is(err, tc.expectedErr) {
+			t.Fatalf("%d: Expected error %v but got %v", i+1, tc.expectedErr, err)
+		}
+	}
+}
+
+func TestVerifyLicenseFile(t *testing.T) {
+	priv := newTestECKey(t)
+	lv, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	lic := signTestLicense(t, jwa.ES384, priv, map[string]interface{}{deploymentID: "abc123"})
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	for i, content := range []string{lic, lic + "\n", "  " + lic + "\r\n\r\n"} {
+		licInfo, err := lv.VerifyLicenseFile(writeFile("license", content), "abc123")
+		if err != nil {
+			t.Fatalf("%d: Expected license to pass verification but failed with %s", i+1, err)
+		}
+		if licInfo.DeploymentID != "abc123" || licInfo.LicenseToken != lic {
+			t.Fatalf("%d: Unexpected license info %v", i+1, licInfo)
+		}
+	}
+
+	testCases := []struct {
+		path        string
+		depID       string
+		expectedErr error
+		errMsg      string
+	}{
+		{writeFile("license", lic), "def456", ErrDeploymentMismatch, ""},
+		{writeFile("invalid", "not-a-license\n"), "abc123", ErrInvalidSignature, ""},
+		{writeFile("empty", " \n"), "abc123", nil, "is empty"},
+		{filepath.Join(dir, "missing"), "abc123", os.ErrNotExist, filepath.Join(dir, "missing")},
+	}
+	for i, tc := range testCases {
+		_, err := lv.VerifyLicenseFile(tc.path, tc.depID)
+		if err == nil {
+			t.Fatalf("%d: Expected license file to fail verification", i+1)
+		}
+		if tc.expectedErr != nil && !errors.Is(err, tc.expectedErr) {
+			t.Fatalf("%d: Expected error %v but got %v", i+1, tc.expectedErr, err)
+		}
+		if !strings.Contains(err.Error(), tc.errMsg) {
+			t.Fatalf("%d: Expected error to mention %q, got %s", i+1, tc.errMsg, err)
+		}
+	}
+
+	// Licenses in their grace period are returned with ErrInGracePeriod,
+	// unless issued for another deployment.
+	expired := signTestLicense(t, jwa.ES384, priv, map[string]interface{}{deploymentID: "abc123", jwt.ExpirationKey: time.Now().Add(-time.Hour)})
+	path := writeFile("expired", expired)
+	licInfo, err := lv.VerifyLicenseFile(path, "abc123", WithGracePeriod(2*time.Hour))
+	if !errors.Is(err, ErrInGracePeriod) {
+		t.Fatalf("Expected license in grace period error, got %v", err)
+	}
+	if licInfo.DeploymentID != "abc123" || licInfo.LicenseToken != expired {
+		t.Fatalf("Expected license info along with the grace period error, got %v", licInfo)
+	}
+	if licInfo, err = lv.VerifyLicenseFile(path, "def456", WithGracePeriod(2*time.Hour)); !errors.Is(err, ErrDeploymentMismatch) || licInfo.LicenseToken != "" {
+		t.Fatalf("Expected deployment mismatch without license info, got %v: %v", err, licInfo)
+	}
+}
+
+func TestVerifyClusterLicensePattern(t *testing.T) {
+	priv := newTestECKey(t)
+	lv, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	lic := signTestLicense(t, jwa.ES384, priv, map[string]interface{}{deploymentID: "tenant-acme-01"})
+
+	testCases := []struct {
+		lic         string
+		pattern     string
+		expectedErr error
+	}{
+		{lic, "tenant-acme-*", nil},
+		{lic, "tenant-*", nil},
+		{lic, "*", nil},
+		{lic, "tenant-acme-01", nil},
+		{lic, "tenant-acme-01*", nil},
+		{lic, "", nil},
+		{lic, "tenant-globex-*", ErrDeploymentMismatch},
+		{lic, "tenant-acme-0", ErrDeploymentMismatch},
+		{lic, "*-acme-01", ErrDeploymentMismatch},
+		{signTestLicense(t, jwa.ES384, priv, nil), "tenant-acme-*", ErrDeploymentMismatch},
+		{signTestLicense(t, jwa.ES384, newTestECKey(t), map[string]interface{}{deploymentID: "tenant-acme-01"}), "tenant-acme-*", ErrInvalidSignature},
+	}
+	for i, tc := range testCases {
+		err := VerifyClusterLicensePattern(lv, tc.lic, tc.pattern)
+		if !errors.Is(err, tc.expectedErr) || (tc.expectedErr == nil && err != nil) {
+			t.Fatalf("%d: Expected error %v but got %v", i+1, tc.expectedErr, err)
+		}
+	}
+}
+
+// TestCheckDeploymentID tests the outcomes of the constant time comparison of
+// deployment IDs.
+func TestCheckDeploymentID(t *testing.T) {
+	testCases := []struct {
+		licID, depID string
+		match        bool
+	}{
+		{"abc123", "abc123", true},
+		{"", "", true},
+		{"abc123", "abc124", false},
+		{"abc123", "abc12", false},
+		{"abc12", "abc123", false},
+		{"abc123", "", false},
+		{"", "abc123", false},
+		{"ABC123", "abc123", false},
+	}
+	for i, tc := range testCases {
+		err := checkDeploymentID(tc.licID, tc.depID)
+		if tc.match && err != nil {
+			t.Fatalf("%d: Expected %q to match %q but got %s", i+1, tc.licID, tc.depID, err)
+		}
+		if !tc.match && !errors.Is(err, ErrDeploymentMismatch) {
+			t.Fatalf("%d: Expected %v but got %v", i+1, ErrDeploymentMismatch, err)
+		}
+	}
+}
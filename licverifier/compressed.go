@@ -0,0 +1,72 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// maxDecompressedLicenseSize is the maximum size of a license decompressed by
+// VerifyCompressed, far above the size of real licenses.
+const maxDecompressedLicenseSize = 64 << 10
+
+// gzipMagic starts every gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// VerifyCompressed verifies the license key in data, gzip compressed or in
+// plain text, and checks that it was issued for the deployment deploymentID
+// like VerifyLicenseFile. data is decompressed if it starts with the gzip
+// magic bytes; decompressed licenses larger than 64 KiB are rejected.
+// Leading and trailing whitespace is removed from the license.
+func (lv *LicenseVerifier) VerifyCompressed(data []byte, deploymentID string, options ...jwt.ParseOption) (LicenseInfo, error) {
+	if bytes.HasPrefix(data, gzipMagic) {
+		var err error
+		if data, err = gunzipLicense(data); err != nil {
+			return LicenseInfo{}, err
+		}
+	}
+	lic := string(bytes.TrimSpace(data))
+	if lic == "" {
+		return LicenseInfo{}, errors.New("license is empty")
+	}
+	return lv.verifyDeployment(lic, deploymentID, options)
+}
+
+// gunzipLicense returns the decompressed content of the gzip stream data, at
+// most maxDecompressedLicenseSize bytes.
+func gunzipLicense(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid compressed license: %w", err)
+	}
+	defer zr.Close()
+	lic, err := io.ReadAll(io.LimitReader(zr, maxDecompressedLicenseSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("invalid compressed license: %w", err)
+	}
+	if len(lic) > maxDecompressedLicenseSize {
+		return nil, fmt.Errorf("compressed license exceeds %d bytes once decompressed", maxDecompressedLicenseSize)
+	}
+	return lic, nil
+}
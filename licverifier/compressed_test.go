@@ -0,0 +1,104 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// gzipBytes returns data gzip compressed.
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestVerifyCompressed(t *testing.T) {
+	priv := newTestECKey(t)
+	lv, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	lic := signTestLicense(t, jwa.ES384, priv, map[string]interface{}{deploymentID: "abc123"})
+	expired := signTestLicense(t, jwa.ES384, priv, map[string]interface{}{
+		deploymentID:      "abc123",
+		jwt.ExpirationKey: time.Now().Add(-time.Hour),
+	})
+	compressed := gzipBytes(t, []byte(lic+"\n"))
+
+	testCases := []struct {
+		data        []byte
+		depID       string
+		expectedErr error
+		errContains string
+	}{
+		{compressed, "abc123", nil, ""},
+		{[]byte(lic), "abc123", nil, ""},
+		{[]byte(" " + lic + "\n"), "abc123", nil, ""},
+		{compressed, "def456", ErrDeploymentMismatch, ""},
+		{gzipBytes(t, []byte(expired)), "abc123", ErrLicenseExpired, ""},
+		{compressed[:len(compressed)/2], "abc123", nil, "invalid compressed license"},
+		{gzipBytes(t, bytes.Repeat([]byte{'a'}, maxDecompressedLicenseSize+1)), "abc123", nil, "exceeds"},
+		{gzipBytes(t, []byte("\n")), "abc123", nil, "license is empty"},
+		{nil, "abc123", nil, "license is empty"},
+	}
+	for i, tc := range testCases {
+		li, err := lv.VerifyCompressed(tc.data, tc.depID)
+		switch {
+		case tc.expectedErr != nil:
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("%d: Expected error %v but got %v", i+1, tc.expectedErr, err)
+			}
+		case tc.errContains != "":
+			if err == nil || !strings.Contains(err.Error(), tc.errContains) {
+				t.Fatalf("%d: Expected error containing %q but got %v", i+1, tc.errContains, err)
+			}
+		default:
+			if err != nil {
+				t.Fatalf("%d: Expected license to pass verification but failed with %s", i+1, err)
+			}
+			if li.LicenseToken != lic {
+				t.Fatalf("%d: Expected license token %q but got %q", i+1, lic, li.LicenseToken)
+			}
+		}
+	}
+
+	// Licenses in their grace period are returned with ErrInGracePeriod.
+	li, err := lv.VerifyCompressed(gzipBytes(t, []byte(expired)), "abc123", WithGracePeriod(2*time.Hour))
+	if !errors.Is(err, ErrInGracePeriod) {
+		t.Fatalf("Expected license in grace period error, got %v", err)
+	}
+	if li.LicenseToken != expired {
+		t.Fatalf("Expected license info along with the grace period error, got %v", li)
+	}
+}
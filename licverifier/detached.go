@@ -0,0 +1,42 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// VerifyDetached verifies the license made of the claims JSON payload and the
+// JWS signature with detached payload, i.e. header..signature, and checks
+// that it was issued for the deployment deploymentID like VerifyLicenseFile.
+// The signature covers the exact bytes of payload: it must be stored and
+// passed unchanged, re-encoding the JSON, even without changing the claims,
+// invalidates the license. The license token of the returned info is the
+// license rebuilt with the payload attached.
+func (lv *LicenseVerifier) VerifyDetached(payload []byte, signature, deploymentID string, options ...jwt.ParseOption) (LicenseInfo, error) {
+	parts := strings.Split(strings.TrimSpace(signature), ".")
+	if len(parts) != 3 || parts[1] != "" {
+		return LicenseInfo{}, errors.New("detached signature must be in the form header..signature")
+	}
+	lic := parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + parts[2]
+	return lv.verifyDeployment(lic, deploymentID, options)
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyDetached(t *testing.T) {
+	priv := newTestECKey(t)
+	lv, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	ls, err := NewLicenseSigner(ecPrivateKeyPEM(t, priv))
+	if err != nil {
+		t.Fatalf("Failed to create license signer: %s", err)
+	}
+	info := LicenseInfo{
+		Email:           "jane@example.com",
+		Subject:         "jane@example.com",
+		Organization:    "Acme",
+		AccountID:       42,
+		DeploymentID:    "abc123",
+		StorageCapacity: 10,
+		Cap:             10,
+		Plan:            "STANDARD",
+		ExpiresAt:       time.Now().Add(time.Hour).Truncate(time.Second),
+	}
+	payload, signature, err := ls.SignDetached(info)
+	if err != nil {
+		t.Fatalf("Failed to sign license: %s", err)
+	}
+	if !strings.Contains(signature, "..") {
+		t.Fatalf("Expected a detached signature but got %s", signature)
+	}
+	other, _, err := ls.SignDetached(LicenseInfo{
+		Email:           "jane@example.com",
+		Organization:    "Acme",
+		AccountID:       42,
+		DeploymentID:    "abc123",
+		StorageCapacity: 1000,
+		Plan:            "STANDARD",
+		ExpiresAt:       info.ExpiresAt,
+	})
+	if err != nil {
+		t.Fatalf("Failed to sign license: %s", err)
+	}
+	reencoded := bytes.ReplaceAll(payload, []byte(","), []byte(", "))
+
+	testCases := []struct {
+		payload     []byte
+		signature   string
+		depID       string
+		expectedErr error
+		errContains string
+	}{
+		{payload, signature, "abc123", nil, ""},
+		{payload, signature + "\n", "abc123", nil, ""},
+		{payload, signature, "def456", ErrDeploymentMismatch, ""},
+		{other, signature, "abc123", ErrInvalidSignature, ""},
+		{reencoded, signature, "abc123", ErrInvalidSignature, ""},
+		{payload, strings.Replace(signature, "..", ".e30.", 1), "abc123", nil, "header..signature"},
+		{payload, "not a signature", "abc123", nil, "header..signature"},
+	}
+	for i, tc := range testCases {
+		li, err := lv.VerifyDetached(tc.payload, tc.signature, tc.depID)
+		switch {
+		case tc.expectedErr != nil:
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("%d: Expected error %v but got %v", i+1, tc.expectedErr, err)
+			}
+		case tc.errContains != "":
+			if err == nil || !strings.Contains(err.Error(), tc.errContains) {
+				t.Fatalf("%d: Expected error containing %q but got %v", i+1, tc.errContains, err)
+			}
+		default:
+			if err != nil {
+				t.Fatalf("%d: Expected license to pass verification but failed with %s", i+1, err)
+			}
+			expected := info
+			expected.LicenseToken = li.LicenseToken
+			if !expected.Equal(li) {
+				t.Fatalf("%d: Expected license info %v but got %v", i+1, expected, li)
+			}
+		}
+	}
+
+	// Licenses in their grace period are returned with ErrInGracePeriod.
+	expired := info
+	expired.ExpiresAt = time.Now().Add(-time.Hour).Truncate(time.Second)
+	if payload, signature, err = ls.SignDetached(expired); err != nil {
+		t.Fatalf("Failed to sign license: %s", err)
+	}
+	li, err := lv.VerifyDetached(payload, signature, "abc123", WithGracePeriod(2*time.Hour))
+	if !errors.Is(err, ErrInGracePeriod) {
+		t.Fatalf("Expected license in grace period error, got %v", err)
+	}
+	if expired.LicenseToken = li.LicenseToken; !expired.Equal(li) {
+		t.Fatalf("Expected license info %v along with the grace period error, got %v", expired, li)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// Diagnose runs the checks of Verify on license independently and returns
+// every failure encountered, so that all the problems of a license are
+// reported at once rather than the first one only. The checks are the
+// signature, the expiry, the not-before time, the other claim validations,
+// the issuer, the required claims, the claim values, the deployment ID and,
+// if configured, the allowed accounts and plans, the revocation list, the
+// usage and the validators. The claims are still checked when the signature
+// isn't valid. An empty depID skips the deployment check. A valid license
+// returns an empty slice.
+//
+// Diagnose is a troubleshooting aid: use Verify to decide whether a license
+// is valid.
+func (lv *LicenseVerifier) Diagnose(license, depID string, options ...jwt.ParseOption) []error {
+	vo, options := splitOptions(options)
+	ctx := context.Background()
+
+	var errs []error
+	keys, baseOpts := lv.keysWithOptions()
+	token, err := parse(license, keys, baseOpts, options)
+	if err != nil {
+		errs = append(errs, signatureError(err))
+		if token, err = jwt.ParseString(license, jwt.WithValidate(false)); err != nil {
+			return append(errs, fmt.Errorf("%w: %s", ErrMalformedClaims, err))
+		}
+	}
+
+	// Each time claim is validated on a copy of the token without the other
+	// ones. Failures of the validators given in options are reported once.
+	validateOpts := validateOptions(ctx, options, vo)
+	seen := make(map[string]bool)
+	for _, removed := range [][]string{
+		{jwt.NotBeforeKey, jwt.IssuedAtKey},
+		{jwt.ExpirationKey, jwt.IssuedAtKey},
+		{jwt.ExpirationKey, jwt.NotBeforeKey},
+	} {
+		partial, err := withoutClaims(token, removed...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrMalformedClaims, err))
+			break
+		}
+		if err = jwt.Validate(partial, validateOpts...); err == nil || seen[err.Error()] {
+			continue
+		}
+		seen[err.Error()] = true
+		if err == jwt.ErrTokenExpired() && inGracePeriod(partial, vo.gracePeriod, validateOpts) {
+			errs = append(errs, fmt.Errorf("%w: expired on %s", ErrInGracePeriod, token.Expiration()))
+			continue
+		}
+		errs = append(errs, validationError(err))
+	}
+
+	if err = checkIssuer(ctx, token, vo); err != nil {
+		errs = append(errs, err)
+	}
+	for _, name := range vo.requiredClaims {
+		if _, ok := token.Get(name); !ok {
+			errs = append(errs, fmt.Errorf("%w: missing %s", ErrMalformedClaims, name))
+		}
+	}
+
+	vo.requiredClaims = nil
+	li, infoErr := toLicenseInfo(license, token, vo)
+	if infoErr != nil {
+		errs = append(errs, infoErr)
+		// The deployment ID is optional, check it even if other claims are
+		// invalid.
+		li.DeploymentID, _ = token.PrivateClaims()[deploymentID].(string)
+	}
+	if depID != "" {
+		if err = checkDeploymentID(li.DeploymentID, depID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if infoErr != nil {
+		return errs
+	}
+	if vo.accountIDs != nil {
+		if _, ok := vo.accountIDs[li.AccountID]; !ok {
+			errs = append(errs, fmt.Errorf("%w: account %d", ErrAccountNotAllowed, li.AccountID))
+		}
+	}
+	if vo.parentAccountIDs != nil {
+		if _, ok := vo.parentAccountIDs[li.ParentAccountID]; !ok {
+			errs = append(errs, fmt.Errorf("%w: parent account %d", ErrAccountNotAllowed, li.ParentAccountID))
+		}
+	}
+	if err = checkPlan(li, vo); err != nil {
+		errs = append(errs, err)
+	}
+	if vo.revocationList.IsRevoked(li.DeploymentID) {
+		errs = append(errs, fmt.Errorf("%w: deployment %s", ErrLicenseRevoked, li.DeploymentID))
+	}
+	if err = checkUsage(li, vo); err != nil {
+		errs = append(errs, err)
+	}
+	if err = runValidators(li, vo); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// withoutClaims returns a copy of token without the claims names.
+func withoutClaims(token jwt.Token, names ...string) (jwt.Token, error) {
+	partial, err := token.Clone()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if err = partial.Remove(name); err != nil {
+			return nil, err
+		}
+	}
+	return partial, nil
+}
+
+// SelfTest verifies sampleLicense, a known-good license, to check at startup
+// that the verifier is configured with the right keys. The sample must be
+// signed by the authority whose licenses are expected: SelfTest fails if its
+// signature can't be validated with the trusted keys or its claims are
+// invalid. The expiry of the sample is ignored and, like Verify, SelfTest
+// doesn't check its deployment ID.
+func (lv *LicenseVerifier) SelfTest(sampleLicense string) error {
+	_, err := lv.Verify(sampleLicense, WithIgnoreExpiry())
+	if err != nil && !errors.Is(err, ErrLicenseExpired) {
+		return fmt.Errorf("license verifier self-test failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+func TestDiagnose(t *testing.T) {
+	priv := newTestECKey(t)
+	lv, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+
+	testCases := []struct {
+		lic          string
+		depID        string
+		options      []jwt.ParseOption
+		expectedErrs []error
+	}{
+		{signTestLicense(t, jwa.ES384, priv, map[string]interface{}{deploymentID: "abc123"}), "abc123", nil, nil},
+		{signTestLicense(t, jwa.ES384, priv, nil), "", nil, nil},
+		{
+			signTestLicense(t, jwa.ES384, priv, map[string]interface{}{
+				deploymentID:      "abc123",
+				jwt.ExpirationKey: time.Now().Add(-time.Hour),
+			}),
+			"def456", nil,
+			[]error{ErrLicenseExpired, ErrDeploymentMismatch},
+		},
+		{
+			signTestLicense(t, jwa.ES384, newTestECKey(t), map[string]interface{}{
+				jwt.NotBeforeKey: time.Now().Add(time.Hour),
+			}),
+			"", nil,
+			[]error{ErrInvalidSignature, ErrNotYetValid},
+		},
+		{
+			signTestLicense(t, jwa.ES384, priv, map[string]interface{}{
+				jwt.IssuerKey:     "someone else",
+				jwt.ExpirationKey: time.Now().Add(-time.Minute),
+			}),
+			"", []jwt.ParseOption{WithExpectedIssuer("subnet"), WithRequiredClaims(licenseID), WithGracePeriod(time.Hour)},
+			[]error{ErrInGracePeriod, ErrInvalidIssuer, ErrMalformedClaims},
+		},
+		{
+			signTestLicense(t, jwa.ES384, priv, map[string]interface{}{
+				organization: nil,
+				deploymentID: "abc123",
+			}),
+			"def456", nil,
+			[]error{ErrMalformedClaims, ErrDeploymentMismatch},
+		},
+		{
+			signTestLicense(t, jwa.ES384, priv, map[string]interface{}{deploymentID: "abc123"}),
+			"abc123", []jwt.ParseOption{WithAllowedAccountIDs(2), WithRevocationList(NewRevocationList("abc123"))},
+			[]error{ErrAccountNotAllowed, ErrLicenseRevoked},
+		},
+		{"not a license", "abc123", nil, []error{ErrInvalidSignature, ErrMalformedClaims}},
+	}
+	for i, tc := range testCases {
+		errs := lv.Diagnose(tc.lic, tc.depID, tc.options...)
+		if len(errs) != len(tc.expectedErrs) {
+			t.Fatalf("%d: Expected errors %v but got %v", i+1, tc.expectedErrs, errs)
+		}
+		for j, expectedErr := range tc.expectedErrs {
+			if !errors.Is(errs[j], expectedErr) {
+				t.Fatalf("%d: Expected errors %v but got %v", i+1, tc.expectedErrs, errs)
+			}
+		}
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	priv := newTestECKey(t)
+	lv, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+
+	testCases := []struct {
+		lic         string
+		expectedErr error
+	}{
+		{signTestLicense(t, jwa.ES384, priv, nil), nil},
+		{signTestLicense(t, jwa.ES384, priv, map[string]interface{}{deploymentID: "abc123"}), nil},
+		{signTestLicense(t, jwa.ES384, priv, map[string]interface{}{jwt.ExpirationKey: time.Now().Add(-time.Hour)}), nil},
+		{signTestLicense(t, jwa.ES384, newTestECKey(t), nil), ErrInvalidSignature},
+		{signTestLicense(t, jwa.ES384, priv, map[string]interface{}{organization: nil}), ErrMalformedClaims},
+		{"not a license", ErrInvalidSignature},
+	}
+	for i, tc := range testCases {
+		err := lv.SelfTest(tc.lic)
+		if tc.expectedErr == nil && err != nil {
+			t.Fatalf("%d: Expected self-test to pass but failed with %s", i+1, err)
+		}
+		if !errors.Is(err, tc.expectedErr) {
+			t.Fatalf("%d: Expected error %v but got %v", i+1, tc.expectedErr, err)
+		}
+	}
+}
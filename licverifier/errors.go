@@ -0,0 +1,143 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// Errors returned by the license verifier, wrapped with more context. Use
+// errors.Is to check for them.
+var (
+	// ErrInvalidSignature is returned when the license can't be parsed or
+	// isn't signed by a trusted key.
+	ErrInvalidSignature = errors.New("invalid license signature")
+	// ErrUnsupportedAlgorithm is returned when the license is signed with an
+	// algorithm none of the trusted keys is registered for.
+	ErrUnsupportedAlgorithm = errors.New("unsupported license signature algorithm")
+	// ErrLicenseExpired is returned when the license has expired.
+	ErrLicenseExpired = errors.New("license has expired")
+	// ErrNotYetValid is returned when the license is used before the time
+	// set by its nbf claim.
+	ErrNotYetValid = errors.New("license is not valid yet")
+	// ErrInGracePeriod is returned along with the license info when the
+	// license has expired but is still within the grace period set by
+	// WithGracePeriod.
+	ErrInGracePeriod = errors.New("license has expired and is in grace period")
+	// ErrInvalidIssuer is returned when the license wasn't issued by the
+	// issuer set by WithExpectedIssuer.
+	ErrInvalidIssuer = errors.New("license issuer doesn't match")
+	// ErrDeploymentMismatch is returned when the license was issued for a
+	// different deployment.
+	ErrDeploymentMismatch = errors.New("license deployment ID doesn't match")
+	// ErrLicenseRevoked is returned when the license has been revoked.
+	ErrLicenseRevoked = errors.New("license has been revoked")
+	// ErrAccountNotAllowed is returned when the license was issued to an
+	// account not allowed by WithAllowedAccountIDs.
+	ErrAccountNotAllowed = errors.New("license account isn't allowed")
+	// ErrPlanNotAllowed is returned when the plan of the license isn't one
+	// of the plans allowed by WithAllowedPlans.
+	ErrPlanNotAllowed = errors.New("license plan isn't allowed")
+	// ErrCapacityExceeded is returned when the usage set by WithUsage
+	// exceeds the storage capacity of the license.
+	ErrCapacityExceeded = errors.New("license capacity exceeded")
+	// ErrMalformedClaims is returned when a claim in the license is missing
+	// or invalid.
+	ErrMalformedClaims = errors.New("malformed license claims")
+	// ErrStreamRead is returned by VerifyStream when the licenses can't be
+	// read, as opposed to licenses failing verification.
+	ErrStreamRead = errors.New("unable to read licenses")
+)
+
+// VerifyError is the error returned by Verify when a license signed by a
+// trusted key is rejected because it has expired, has been revoked, its
+// account isn't allowed by WithAllowedAccountIDs or
+// WithAllowedParentAccountIDs, its plan isn't allowed by WithAllowedPlans,
+// its capacity is exceeded by the usage set by WithUsage or a validator set
+// by WithValidator rejected it. Info holds the claims of the license, so that
+// callers can still show who the license was issued to; it is zero if the
+// claims couldn't be extracted.
+type VerifyError struct {
+	Info LicenseInfo
+	Err  error
+}
+
+func (e *VerifyError) Error() string { return e.Err.Error() }
+
+// Unwrap returns the cause of the error.
+func (e *VerifyError) Unwrap() error { return e.Err }
+
+// errInvalidClaim returns an ErrMalformedClaims error for the claim name.
+func errInvalidClaim(name string) error {
+	return fmt.Errorf("%w: invalid %s", ErrMalformedClaims, name)
+}
+
+// validationError maps an error returned by jwt.Validate to the matching
+// licverifier error.
+func validationError(err error) error {
+	switch err {
+	case jwt.ErrTokenExpired():
+		return fmt.Errorf("%w: %s", ErrLicenseExpired, err)
+	case jwt.ErrTokenNotYetValid():
+		return fmt.Errorf("%w: %s", ErrNotYetValid, err)
+	default:
+		return fmt.Errorf("%w: %s", ErrMalformedClaims, err)
+	}
+}
+
+// errorCodeInternal is the code of errors not returned by this package.
+const errorCodeInternal = "internal"
+
+// verifyErrorJSON is the JSON representation of a verification error.
+type verifyErrorJSON struct {
+	Code      string     `json:"code"`
+	Message   string     `json:"message"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// VerifyErrorJSON returns the JSON encoding of an error returned by this
+// package, suitable for API responses:
+//
+//	{"code":"expired","message":"license has expired: ...","expiresAt":"2024-03-01T00:00:00Z"}
+//
+// The code is one of the Result constants matching the error, or
+// "deployment_mismatch" for ErrDeploymentMismatch. Other errors have the
+// code "internal" and a generic message. The expiry time is only set when
+// the license info is known, see VerifyError.
+func VerifyErrorJSON(err error) ([]byte, error) {
+	if err == nil {
+		return nil, errors.New("no verification error to encode")
+	}
+	v := verifyErrorJSON{Code: verifyResult(err), Message: err.Error()}
+	switch {
+	case errors.Is(err, ErrDeploymentMismatch):
+		v.Code = "deployment_mismatch"
+	case v.Code == ResultError:
+		v.Code, v.Message = errorCodeInternal, "internal error"
+	}
+	var verr *VerifyError
+	if errors.As(err, &verr) && !verr.Info.ExpiresAt.IsZero() {
+		v.ExpiresAt = &verr.Info.ExpiresAt
+	}
+	return json.Marshal(v)
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestVerifyErrorJSON(t *testing.T) {
+	exp := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	expired := fmt.Errorf("%w: exp not satisfied", ErrLicenseExpired)
+
+	testCases := []struct {
+		err      error
+		expected string
+	}{
+		{&VerifyError{Info: LicenseInfo{ExpiresAt: exp}, Err: expired}, `{"code":"expired","message":"license has expired: exp not satisfied","expiresAt":"2024-03-01T00:00:00Z"}`},
+		{&VerifyError{Err: expired}, `{"code":"expired","message":"license has expired: exp not satisfied"}`},
+		{fmt.Errorf("%w: unknown key", ErrInvalidSignature), `{"code":"invalid_signature","message":"invalid license signature: unknown key"}`},
+		{&VerifyError{Info: LicenseInfo{ExpiresAt: exp}, Err: fmt.Errorf("%w: deployment abc123", ErrLicenseRevoked)}, `{"code":"revoked","message":"license has been revoked: deployment abc123","expiresAt":"2024-03-01T00:00:00Z"}`},
+		{errInvalidClaim("plan"), `{"code":"malformed_claims","message":"malformed license claims: invalid plan"}`},
+		{fmt.Errorf("%w: expected abc123, got def456", ErrDeploymentMismatch), `{"code":"deployment_mismatch","message":"license deployment ID doesn't match: expected abc123, got def456"}`},
+		{errors.New("open /etc/minio/license: permission denied"), `{"code":"internal","message":"internal error"}`},
+	}
+	for i, tc := range testCases {
+		data, err := VerifyErrorJSON(tc.err)
+		if err != nil {
+			t.Fatalf("%d: Failed to encode error: %s", i+1, err)
+		}
+		if string(data) != tc.expected {
+			t.Fatalf("%d: Expected %s but got %s", i+1, tc.expected, data)
+		}
+	}
+
+	if _, err := VerifyErrorJSON(nil); err == nil {
+		t.Fatal("Expected an error encoding a nil error")
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+)
+
+// WithHTTPClient sets the HTTP client used to fetch remote key sets. It
+// defaults to http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *verifierOptions) {
+		o.httpClient = client
+	}
+}
+
+// WithHTTPHeader adds a header sent with the requests fetching remote key
+// sets, e.g. an Authorization header. It can be given several times.
+func WithHTTPHeader(key, value string) Option {
+	return func(o *verifierOptions) {
+		if o.httpHeader == nil {
+			o.httpHeader = make(http.Header)
+		}
+		o.httpHeader.Add(key, value)
+	}
+}
+
+// WithInsecureHTTP allows fetching remote key sets over plain HTTP. By
+// default, key set URLs must use HTTPS: anyone able to tamper with the
+// traffic of a plain HTTP fetch can make the verifier trust their own keys.
+// It is meant for tests and local development only.
+func WithInsecureHTTP() Option {
+	return func(o *verifierOptions) {
+		o.insecureHTTP = true
+	}
+}
+
+// headerClient is an HTTP client adding headers to the requests it sends.
+type headerClient struct {
+	client jwk.HTTPClient
+	header http.Header
+}
+
+func (c headerClient) Do(req *http.Request) (*http.Response, error) {
+	for key, values := range c.header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	return c.client.Do(req)
+}
+
+// NewLicenseVerifierFromJWKS returns an initialized license verifier trusting
+// the keys of the JSON Web Key Set published at url, which must be an https
+// URL unless WithInsecureHTTP is set. The key set is fetched once, honoring
+// the deadline of ctx, and is only fetched again by Refresh or
+// StartAutoRefresh.
+func NewLicenseVerifierFromJWKS(ctx context.Context, url string, opts ...Option) (*LicenseVerifier, error) {
+	var o verifierOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	keyset, err := fetchKeySet(ctx, url, o)
+	if err != nil {
+		return nil, err
+	}
+	return &LicenseVerifier{
+		keySet:  keyset,
+		jwksURL: url,
+		opts:    o,
+	}, nil
+}
+
+// NewLicenseVerifierFromJWK returns an initialized license verifier trusting
+// the public keys in jwkJSON, either a single JSON Web Key or a JSON Web Key
+// Set. Keys must be ECDSA, RSA or Ed25519 keys; their algorithm, if any, must
+// match the one set by WithAlgorithm.
+func NewLicenseVerifierFromJWK(jwkJSON []byte, opts ...Option) (*LicenseVerifier, error) {
+	var o verifierOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	parsed, err := jwk.Parse(jwkJSON)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON Web Key: %w", err)
+	}
+	if parsed.Len() == 0 {
+		return nil, errors.New("JSON Web Key Set is empty")
+	}
+
+	keyset := jwk.NewSet()
+	for i := 0; i < parsed.Len(); i++ {
+		key, _ := parsed.Get(i)
+		if key, err = publicJWK(key, o); err != nil {
+			return nil, fmt.Errorf("key #%d: %w", i+1, err)
+		}
+		keyset.Add(key)
+	}
+	return &LicenseVerifier{
+		keySet: keyset,
+		opts:   o,
+	}, nil
+}
+
+// Refresh fetches the remote key set again and replaces the trusted keys with
+// it. The trusted keys are left unchanged if the fetch fails. Refresh returns
+// an error for verifiers not created by NewLicenseVerifierFromJWKS.
+func (lv *LicenseVerifier) Refresh(ctx context.Context) error {
+	if lv.jwksURL == "" {
+		return errors.New("license verifier has no remote key set")
+	}
+	keyset, err := fetchKeySet(ctx, lv.jwksURL, lv.opts)
+	if err != nil {
+		return err
+	}
+	lv.mu.Lock()
+	lv.keySet = keyset
+	lv.mu.Unlock()
+	return nil
+}
+
+// refreshErrorsSize is the number of refresh failures buffered by the channel
+// returned by StartAutoRefresh.
+const refreshErrorsSize = 8
+
+// StartAutoRefresh starts a goroutine calling Refresh every interval until
+// ctx is canceled. Failed refreshes keep the previously fetched keys and
+// their errors are sent to the returned channel, e.g. for alerting. The
+// channel buffers a few errors and drops the next ones until they are
+// received, so that a slow consumer never delays the refreshes; it is closed
+// once ctx is canceled. For verifiers not created by NewLicenseVerifierFromJWKS,
+// StartAutoRefresh does nothing and returns a closed channel. It doesn't start
+// the goroutine either if interval isn't positive, the returned channel then
+// carries an error and is closed.
+func (lv *LicenseVerifier) StartAutoRefresh(ctx context.Context, interval time.Duration) <-chan error {
+	errs := make(chan error, refreshErrorsSize)
+	if lv.jwksURL == "" {
+		close(errs)
+		return errs
+	}
+	if interval <= 0 {
+		errs <- fmt.Errorf("key set refresh interval %s must be positive", interval)
+		close(errs)
+		return errs
+	}
+	go func() {
+		defer close(errs)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := lv.Refresh(ctx); err != nil && ctx.Err() == nil {
+					select {
+					case errs <- err:
+					default:
+					}
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return errs
+}
+
+// fetchKeySet fetches the JSON Web Key Set at url and returns the public keys
+// in it, each carrying the algorithm used to verify signatures with it.
+func fetchKeySet(ctx context.Context, url string, o verifierOptions) (jwk.Set, error) {
+	if err := checkKeySetURL(url, o); err != nil {
+		return nil, err
+	}
+	var client jwk.HTTPClient = http.DefaultClient
+	if o.httpClient != nil {
+		client = o.httpClient
+	}
+	if len(o.httpHeader) > 0 {
+		client = headerClient{client: client, header: o.httpHeader}
+	}
+	fetched, err := jwk.Fetch(ctx, url, jwk.WithHTTPClient(client))
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch key set from %s: %w", url, err)
+	}
+	if fetched.Len() == 0 {
+		return nil, fmt.Errorf("key set from %s is empty", url)
+	}
+
+	keyset := jwk.NewSet()
+	for i := 0; i < fetched.Len(); i++ {
+		key, _ := fetched.Get(i)
+		if key, err = publicJWK(key, o); err != nil {
+			return nil, fmt.Errorf("key #%d from %s: %w", i+1, url, err)
+		}
+		keyset.Add(key)
+	}
+	return keyset, nil
+}
+
+// checkKeySetURL returns an error if the key set at rawURL isn't fetched over
+// HTTPS, or plain HTTP if allowed by WithInsecureHTTP.
+func checkKeySetURL(rawURL string, o verifierOptions) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid key set URL %q: %w", rawURL, err)
+	}
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		if o.insecureHTTP {
+			return nil
+		}
+	}
+	return fmt.Errorf("key set URL %q must use https", rawURL)
+}
+
+// publicJWK returns the public part of key, carrying a signature algorithm
+// compatible with the key type.
+func publicJWK(key jwk.Key, o verifierOptions) (jwk.Key, error) {
+	pub, err := key.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	var raw interface{}
+	if err = pub.Raw(&raw); err != nil {
+		return nil, err
+	}
+	switch raw.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T, expected ECDSA, RSA or Ed25519", raw)
+	}
+
+	alg := jwa.SignatureAlgorithm(pub.Algorithm())
+	switch {
+	case o.alg != "":
+		if alg != "" && alg != o.alg {
+			return nil, fmt.Errorf("key algorithm %s doesn't match %s", alg, o.alg)
+		}
+		alg = o.alg
+	case alg == "":
+		alg = signatureAlgorithm(raw)
+	}
+	if err = checkAlgorithm(raw, alg); err != nil {
+		return nil, err
+	}
+	pub.Set(jwk.AlgorithmKey, alg)
+	return pub, nil
+}
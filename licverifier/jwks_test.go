@@ -0,0 +1,441 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+)
+
+// jwksServer serves the public keys of the given private keys as a JSON Web
+// Key Set. The served keys can be replaced with setKeys.
+type jwksServer struct {
+	*httptest.Server
+
+	mu   sync.Mutex
+	keys []*ecdsa.PrivateKey
+}
+
+func newJWKSServer(t *testing.T, keys ...*ecdsa.PrivateKey) *jwksServer {
+	t.Helper()
+	s := &jwksServer{keys: keys}
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		set := jwk.NewSet()
+		for _, priv := range s.keys {
+			key, err := jwk.New(&priv.PublicKey)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			set.Add(key)
+		}
+		json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) setKeys(keys ...*ecdsa.PrivateKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+func newTestECKey(t testing.TB) *ecdsa.PrivateKey {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv
+}
+
+// TestNewLicenseVerifierFromJWKS tests verifying licenses against a key set
+// fetched over HTTPS.
+func TestNewLicenseVerifierFromJWKS(t *testing.T) {
+	priv1, priv2 := newTestECKey(t), newTestECKey(t)
+	ts := newJWKSServer(t, priv1, priv2)
+
+	// The test server certificate is only trusted by its own client.
+	if _, err := NewLicenseVerifierFromJWKS(context.Background(), ts.URL); err == nil {
+		t.Fatal("Expected fetch with an untrusted certificate to fail")
+	}
+
+	lv, err := NewLicenseVerifierFromJWKS(context.Background(), ts.URL, WithHTTPClient(ts.Client()))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	for i, priv := range []*ecdsa.PrivateKey{priv1, priv2} {
+		if _, err = lv.Verify(signTestLicense(t, jwa.ES384, priv, nil)); err != nil {
+			t.Fatalf("%d: Expected license to pass verification but failed with %s", i+1, err)
+		}
+	}
+	if _, err = lv.Verify(signTestLicense(t, jwa.ES384, newTestECKey(t), nil)); err == nil {
+		t.Fatal("Expected license signed by an untrusted key to fail verification")
+	}
+}
+
+// TestNewLicenseVerifierFromJWK tests verifying licenses against keys given
+// as a single JSON Web Key or a JSON Web Key Set.
+func TestNewLicenseVerifierFromJWK(t *testing.T) {
+	priv1, priv2 := newTestECKey(t), newTestECKey(t)
+	jwkJSON := func(key interface{}, alg jwa.SignatureAlgorithm) []byte {
+		t.Helper()
+		k, err := jwk.New(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if alg != "" {
+			k.Set(jwk.AlgorithmKey, alg)
+		}
+		b, err := json.Marshal(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	key1, key2 := jwkJSON(&priv1.PublicKey, ""), jwkJSON(&priv2.PublicKey, "")
+	set := []byte(fmt.Sprintf(`{"keys":[%s,%s]}`, key1, key2))
+
+	lv, err := NewLicenseVerifierFromJWK(key1)
+	if err != nil {
+		t.Fatalf("Failed to create license verifier from a key: %s", err)
+	}
+	if _, err = lv.Verify(signTestLicense(t, jwa.ES384, priv1, nil)); err != nil {
+		t.Fatalf("Expected license to pass verification but failed with %s", err)
+	}
+	if _, err = lv.Verify(signTestLicense(t, jwa.ES384, priv2, nil)); err == nil {
+		t.Fatal("Expected license signed by an untrusted key to fail verification")
+	}
+
+	lv, err = NewLicenseVerifierFromJWK(set)
+	if err != nil {
+		t.Fatalf("Failed to create license verifier from a key set: %s", err)
+	}
+	for i, priv := range []*ecdsa.PrivateKey{priv1, priv2} {
+		if _, err = lv.Verify(signTestLicense(t, jwa.ES384, priv, nil)); err != nil {
+			t.Fatalf("%d: Expected license to pass verification but failed with %s", i+1, err)
+		}
+	}
+
+	testCases := []struct {
+		jwkJSON []byte
+		opts    []Option
+	}{
+		{[]byte(""), nil},
+		{[]byte("not a key"), nil},
+		{[]byte(`{"keys":[]}`), nil},
+		{jwkJSON([]byte("symmetric secret"), ""), nil},
+		{jwkJSON(&priv1.PublicKey, jwa.RS256), nil},
+		{jwkJSON(&priv1.PublicKey, jwa.ES256), []Option{WithAlgorithm(jwa.ES384)}},
+		{key1, []Option{WithAlgorithm(jwa.EdDSA)}},
+	}
+	for i, tc := range testCases {
+		if _, err = NewLicenseVerifierFromJWK(tc.jwkJSON, tc.opts...); err == nil {
+			t.Fatalf("%d: Expected creating the license verifier to fail", i+1)
+		}
+	}
+}
+
+// TestWithHTTPHeader tests that the custom headers are sent with the fetch
+// requests, including those of Refresh.
+func TestWithHTTPHeader(t *testing.T) {
+	priv := newTestECKey(t)
+	key, err := jwk.New(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := jwk.NewSet()
+	set.Add(key)
+	var requests atomic.Int32
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			http.Error(w, "unexpected authorization "+got, http.StatusUnauthorized)
+			return
+		}
+		if got := r.Header.Values("X-Tenant"); !reflect.DeepEqual(got, []string{"a", "b"}) {
+			http.Error(w, fmt.Sprintf("unexpected tenants %v", got), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(set)
+	}))
+	defer ts.Close()
+
+	if _, err = NewLicenseVerifierFromJWKS(context.Background(), ts.URL, WithHTTPClient(ts.Client())); err == nil {
+		t.Fatal("Expected fetch without the authorization header to fail")
+	}
+	client := ts.Client()
+	client.Timeout = time.Minute
+	lv, err := NewLicenseVerifierFromJWKS(context.Background(), ts.URL,
+		WithHTTPClient(client),
+		WithHTTPHeader("Authorization", "Bearer secret"),
+		WithHTTPHeader("X-Tenant", "a"),
+		WithHTTPHeader("X-Tenant", "b"))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	if _, err = lv.Verify(signTestLicense(t, jwa.ES384, priv, nil)); err != nil {
+		t.Fatalf("Expected license to pass verification but failed with %s", err)
+	}
+	if err = lv.Refresh(context.Background()); err != nil {
+		t.Fatalf("Failed to refresh key set: %s", err)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Fatalf("Expected 3 requests but got %d", n)
+	}
+}
+
+// TestNewLicenseVerifierFromJWKSInsecure tests that key sets are only fetched
+// over plain HTTP with WithInsecureHTTP.
+func TestNewLicenseVerifierFromJWKSInsecure(t *testing.T) {
+	priv := newTestECKey(t)
+	key, err := jwk.New(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := jwk.NewSet()
+	set.Add(key)
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		json.NewEncoder(w).Encode(set)
+	}))
+	defer ts.Close()
+
+	for i, u := range []string{ts.URL, "ftp://example.com/keys.json", "example.com/keys.json", "://"} {
+		if _, err = NewLicenseVerifierFromJWKS(context.Background(), u); err == nil {
+			t.Fatalf("%d: Expected key set URL %q to be rejected", i+1, u)
+		}
+	}
+	if n := requests.Load(); n != 0 {
+		t.Fatalf("Expected no request but got %d", n)
+	}
+	lv, err := NewLicenseVerifierFromJWKS(context.Background(), ts.URL, WithInsecureHTTP())
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	if _, err = lv.Verify(signTestLicense(t, jwa.ES384, priv, nil)); err != nil {
+		t.Fatalf("Expected license to pass verification but failed with %s", err)
+	}
+	if err = lv.Refresh(context.Background()); err != nil {
+		t.Fatalf("Failed to refresh key set: %s", err)
+	}
+}
+
+// TestNewLicenseVerifierFromJWKSDeadline tests that the fetch honors the
+// context deadline.
+func TestNewLicenseVerifierFromJWKSDeadline(t *testing.T) {
+	done := make(chan struct{})
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := NewLicenseVerifierFromJWKS(ctx, ts.URL, WithHTTPClient(ts.Client()))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded error, got %v", err)
+	}
+}
+
+// TestStartAutoRefresh tests that rotated keys are picked up by the background
+// refresh while licenses are being verified concurrently.
+func TestStartAutoRefresh(t *testing.T) {
+	oldKey, newKey := newTestECKey(t), newTestECKey(t)
+	ts := newJWKSServer(t, oldKey)
+
+	lv, err := NewLicenseVerifierFromJWKS(context.Background(), ts.URL, WithHTTPClient(ts.Client()))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	oldLic := signTestLicense(t, jwa.ES384, oldKey, nil)
+	newLic := signTestLicense(t, jwa.ES384, newKey, nil)
+	if _, err = lv.Verify(oldLic); err != nil {
+		t.Fatalf("Expected license to pass verification but failed with %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lv.StartAutoRefresh(ctx, 10*time.Millisecond)
+
+	// Every verification must succeed with exactly one of the two licenses,
+	// a torn or empty key set would fail both.
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				_, errOld := lv.Verify(oldLic)
+				_, errNew := lv.Verify(newLic)
+				if errOld != nil && errNew != nil {
+					t.Errorf("Both licenses failed verification: %s, %s", errOld, errNew)
+					return
+				}
+			}
+		}()
+	}
+
+	ts.setKeys(newKey)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err = lv.Verify(oldLic); err != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Rotated key set was not picked up by the refresh")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(stop)
+	wg.Wait()
+
+	if _, err = lv.Verify(newLic); err != nil {
+		t.Fatalf("Expected license signed by the rotated key to pass verification but failed with %s", err)
+	}
+}
+
+// TestStartAutoRefreshErrors tests that refresh failures are reported without
+// stopping the refresh loop, even if they aren't received.
+func TestStartAutoRefreshErrors(t *testing.T) {
+	priv := newTestECKey(t)
+	ts := newJWKSServer(t, priv)
+	lv, err := NewLicenseVerifierFromJWKS(context.Background(), ts.URL, WithHTTPClient(ts.Client()))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+
+	// An empty key set fails to refresh.
+	ts.setKeys()
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := lv.StartAutoRefresh(ctx, time.Millisecond)
+	time.Sleep(50 * time.Millisecond) // let failures pile up beyond the buffer
+	select {
+	case err := <-errs:
+		if err == nil || !strings.Contains(err.Error(), "is empty") {
+			t.Fatalf("Expected empty key set error but got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a refresh error")
+	}
+	if _, err = lv.Verify(signTestLicense(t, jwa.ES384, priv, nil)); err != nil {
+		t.Fatalf("Expected the previous keys to be kept but failed with %s", err)
+	}
+
+	// The loop is still running: rotated keys are picked up.
+	newKey := newTestECKey(t)
+	ts.setKeys(newKey)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err = lv.Verify(signTestLicense(t, jwa.ES384, newKey, nil)); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Rotated key set was not picked up by the refresh")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	for range errs {
+	}
+
+	local, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	if _, ok := <-local.StartAutoRefresh(context.Background(), time.Millisecond); ok {
+		t.Fatal("Expected a closed channel for a verifier without remote key set")
+	}
+
+	for i, interval := range []time.Duration{0, -time.Second} {
+		errs = lv.StartAutoRefresh(context.Background(), interval)
+		if err, ok := <-errs; !ok || err == nil {
+			t.Fatalf("%d: Expected an error for interval %s", i+1, interval)
+		}
+		if _, ok := <-errs; ok {
+			t.Fatalf("%d: Expected the channel to be closed after the error", i+1)
+		}
+	}
+}
+
+// TestKeyIDs tests listing the IDs of trusted keys with and without ID.
+func TestKeyIDs(t *testing.T) {
+	privs := []*ecdsa.PrivateKey{newTestECKey(t), newTestECKey(t), newTestECKey(t)}
+	set := jwk.NewSet()
+	for i, kid := range []string{"2024-01", "", "2024-06"} {
+		key, err := jwk.New(&privs[i].PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kid != "" {
+			key.Set(jwk.KeyIDKey, kid)
+		}
+		set.Add(key)
+	}
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(set)
+	}))
+	defer ts.Close()
+
+	lv, err := NewLicenseVerifierFromJWKS(context.Background(), ts.URL, WithHTTPClient(ts.Client()))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	if ids, expected := lv.KeyIDs(), []string{"2024-01", "", "2024-06"}; !reflect.DeepEqual(ids, expected) {
+		t.Fatalf("Expected key IDs %q but got %q", expected, ids)
+	}
+
+	lv, err = NewLicenseVerifierWithKeys(publicKeyPEM(t, &privs[0].PublicKey), publicKeyPEM(t, &privs[1].PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	if ids, expected := lv.KeyIDs(), []string{"", ""}; !reflect.DeepEqual(ids, expected) {
+		t.Fatalf("Expected key IDs %q but got %q", expected, ids)
+	}
+}
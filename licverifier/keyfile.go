@@ -0,0 +1,81 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// DefaultKeyFilePollInterval is how often WatchKeyFile checks the key file
+// for changes, unless overridden by WithKeyFilePollInterval.
+const DefaultKeyFilePollInterval = 10 * time.Second
+
+// WithKeyFilePollInterval sets how often WatchKeyFile checks the key file
+// for changes. It defaults to DefaultKeyFilePollInterval.
+func WithKeyFilePollInterval(d time.Duration) Option {
+	return func(o *verifierOptions) {
+		o.keyFilePollInterval = d
+	}
+}
+
+// WatchKeyFile replaces the trusted keys of the verifier with the public key
+// in PEM format read from the file at path, then starts a goroutine checking
+// the file for changes until ctx is canceled, so that the verifier follows a
+// key rotated in place, e.g. a mounted Kubernetes secret. The keys are
+// replaced with SetKeys whenever the content of the file changes and parses;
+// the file is checked every DefaultKeyFilePollInterval, or the interval set
+// with WithKeyFilePollInterval. Failures to read or parse the file, such as
+// while it is being replaced, keep the current keys and are retried at the
+// next check. It returns an error, without starting the goroutine, if the
+// file can't be used initially.
+func (lv *LicenseVerifier) WatchKeyFile(ctx context.Context, path string) error {
+	last, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read public key from %s: %w", path, err)
+	}
+	if err = lv.SetKeys(last); err != nil {
+		return fmt.Errorf("invalid public key in %s: %w", path, err)
+	}
+	interval := lv.opts.keyFilePollInterval
+	if interval <= 0 {
+		interval = DefaultKeyFilePollInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				data, err := os.ReadFile(path)
+				if err != nil || bytes.Equal(data, last) {
+					continue
+				}
+				if lv.SetKeys(data) == nil {
+					last = data
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+)
+
+func TestWatchKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "public.pem")
+	// rotate replaces the key file atomically, like a Kubernetes secret.
+	rotate := func(data []byte) {
+		t.Helper()
+		tmp := filepath.Join(dir, "tmp.pem")
+		if err := os.WriteFile(tmp, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// waitFor waits until the license signed by priv verifies.
+	waitFor := func(lv *LicenseVerifier, priv *ecdsa.PrivateKey) {
+		t.Helper()
+		lic := signTestLicense(t, jwa.ES384, priv, nil)
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			if _, err := lv.Verify(lic); err == nil {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("Rotated key was not picked up")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	key1, key2, key3 := newTestECKey(t), newTestECKey(t), newTestECKey(t)
+	lv, err := NewLicenseVerifier(publicKeyPEM(t, &newTestECKey(t).PublicKey), WithKeyFilePollInterval(time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	if err = lv.WatchKeyFile(context.Background(), path); err == nil {
+		t.Fatal("Expected a missing key file to be rejected")
+	}
+	rotate([]byte("garbage"))
+	if err = lv.WatchKeyFile(context.Background(), path); err == nil {
+		t.Fatal("Expected an invalid key file to be rejected")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rotate(publicKeyPEM(t, &key1.PublicKey))
+	if err = lv.WatchKeyFile(ctx, path); err != nil {
+		t.Fatalf("Failed to watch key file: %s", err)
+	}
+	if _, err = lv.Verify(signTestLicense(t, jwa.ES384, key1, nil)); err != nil {
+		t.Fatalf("Expected license to pass verification but failed with %s", err)
+	}
+
+	rotate(publicKeyPEM(t, &key2.PublicKey))
+	waitFor(lv, key2)
+
+	// Unreadable or invalid content keeps the current key.
+	if err = os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	rotate([]byte("-----BEGIN PUBLIC KEY-----\n"))
+	time.Sleep(20 * time.Millisecond)
+	if _, err = lv.Verify(signTestLicense(t, jwa.ES384, key2, nil)); err != nil {
+		t.Fatalf("Expected the current key to be kept but failed with %s", err)
+	}
+	rotate(publicKeyPEM(t, &key3.PublicKey))
+	waitFor(lv, key3)
+
+	// No more changes once ctx is canceled.
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	rotate(publicKeyPEM(t, &key1.PublicKey))
+	time.Sleep(20 * time.Millisecond)
+	if _, err = lv.Verify(signTestLicense(t, jwa.ES384, key3, nil)); err != nil {
+		t.Fatalf("Expected the key to be kept after cancel but failed with %s", err)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"math"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// WithLatencyRecorder makes the verifier record the duration of its last n
+// verifications, as reported by Latency. A non-positive n records nothing.
+func WithLatencyRecorder(n int) Option {
+	return func(o *verifierOptions) {
+		if n > 0 {
+			o.latency = newLatencyRecorder(n)
+		}
+	}
+}
+
+// LatencyRecorder keeps the durations of the last verifications of a
+// verifier in a ring buffer, e.g. for a self-diagnostic endpoint. Recording
+// and reading take no lock; statistics read while verifications are recorded
+// may mix durations of two consecutive windows. The statistics of a nil or
+// empty recorder are zero.
+type LatencyRecorder struct {
+	next    atomic.Uint64 // number of durations recorded so far
+	samples []atomic.Int64
+}
+
+func newLatencyRecorder(n int) *LatencyRecorder {
+	return &LatencyRecorder{samples: make([]atomic.Int64, n)}
+}
+
+// Latency returns the latency recorder set by WithLatencyRecorder, nil if
+// none.
+func (lv *LicenseVerifier) Latency() *LatencyRecorder {
+	return lv.opts.latency
+}
+
+// record adds d to the recorded durations, replacing the oldest one once
+// the buffer is full.
+func (r *LatencyRecorder) record(d time.Duration) {
+	i := r.next.Add(1) - 1
+	r.samples[i%uint64(len(r.samples))].Store(int64(d))
+}
+
+// Count returns the number of recorded durations, at most the size of the
+// recorder.
+func (r *LatencyRecorder) Count() int {
+	if r == nil {
+		return 0
+	}
+	return int(min(r.next.Load(), uint64(len(r.samples))))
+}
+
+// P50 returns the median of the recorded durations.
+func (r *LatencyRecorder) P50() time.Duration {
+	return r.percentile(0.50)
+}
+
+// P95 returns the 95th percentile of the recorded durations.
+func (r *LatencyRecorder) P95() time.Duration {
+	return r.percentile(0.95)
+}
+
+// Max returns the longest of the recorded durations.
+func (r *LatencyRecorder) Max() time.Duration {
+	return r.percentile(1)
+}
+
+// percentile returns the p-th quantile of the recorded durations, using the
+// nearest-rank method.
+func (r *LatencyRecorder) percentile(p float64) time.Duration {
+	n := r.Count()
+	if n == 0 {
+		return 0
+	}
+	sorted := make([]time.Duration, n)
+	for i := range sorted {
+		sorted[i] = time.Duration(r.samples[i].Load())
+	}
+	slices.Sort(sorted)
+	rank := int(math.Ceil(p * float64(n)))
+	return sorted[max(rank, 1)-1]
+}
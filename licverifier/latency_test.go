@@ -0,0 +1,107 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+)
+
+func TestLatencyRecorder(t *testing.T) {
+	r := newLatencyRecorder(10)
+	if r.Count() != 0 || r.P50() != 0 || r.P95() != 0 || r.Max() != 0 {
+		t.Fatal("Expected zero statistics for an empty recorder")
+	}
+	var nilRecorder *LatencyRecorder
+	if nilRecorder.Count() != 0 || nilRecorder.P50() != 0 || nilRecorder.Max() != 0 {
+		t.Fatal("Expected zero statistics for a nil recorder")
+	}
+
+	testCases := []struct {
+		record []time.Duration
+		count  int
+		p50    time.Duration
+		p95    time.Duration
+		max    time.Duration
+	}{
+		{[]time.Duration{5}, 1, 5, 5, 5},
+		{[]time.Duration{1, 9, 3}, 4, 3, 9, 9},
+		{[]time.Duration{2, 4, 6, 8, 10, 12}, 10, 5, 12, 12},
+		// the oldest durations are replaced once the buffer is full
+		{[]time.Duration{20, 20, 20, 20, 20, 20, 20, 20, 20}, 10, 20, 20, 20},
+		{[]time.Duration{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}, 10, 1, 1, 1},
+	}
+	for i, tc := range testCases {
+		for _, d := range tc.record {
+			r.record(d)
+		}
+		if got := r.Count(); got != tc.count {
+			t.Fatalf("%d: Expected %d durations but got %d", i+1, tc.count, got)
+		}
+		if got := r.P50(); got != tc.p50 {
+			t.Fatalf("%d: Expected P50 %v but got %v", i+1, tc.p50, got)
+		}
+		if got := r.P95(); got != tc.p95 {
+			t.Fatalf("%d: Expected P95 %v but got %v", i+1, tc.p95, got)
+		}
+		if got := r.Max(); got != tc.max {
+			t.Fatalf("%d: Expected max %v but got %v", i+1, tc.max, got)
+		}
+	}
+}
+
+func TestWithLatencyRecorder(t *testing.T) {
+	priv := newTestECKey(t)
+	pemBytes := publicKeyPEM(t, &priv.PublicKey)
+	lic := signTestLicense(t, jwa.ES384, priv, nil)
+
+	lv, err := NewLicenseVerifier(pemBytes)
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	if lv.Latency() != nil {
+		t.Fatal("Expected no latency recorder by default")
+	}
+
+	lv, err = NewLicenseVerifier(pemBytes, WithLatencyRecorder(8))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				lv.Verify(lic)
+				lv.Verify("not a license")
+			}
+		}()
+	}
+	wg.Wait()
+	r := lv.Latency()
+	if r.Count() != 8 {
+		t.Fatalf("Expected 8 durations but got %d", r.Count())
+	}
+	if p50, p95, max := r.P50(), r.P95(), r.Max(); p50 <= 0 || p50 > p95 || p95 > max {
+		t.Fatalf("Expected 0 < P50 <= P95 <= max but got %v, %v and %v", p50, p95, max)
+	}
+}
@@ -0,0 +1,490 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// defaultClock is the source of the current time for the LicenseInfo
+// helpers. Tests may override it.
+var defaultClock jwt.Clock = jwt.ClockFunc(time.Now)
+
+// IsExpired returns true if the license has expired. Like the expiry check
+// done by Verify, times are compared with a precision of one second and a
+// license without expiry never expires.
+func (li LicenseInfo) IsExpired() bool {
+	return li.Remaining() == 0
+}
+
+// Remaining returns the time left until the license expires, or zero once it
+// has expired. Licenses without expiry have math.MaxInt64 remaining. The clock
+// set by WithClock when verifying the license is used if any, the system
+// clock otherwise.
+func (li LicenseInfo) Remaining() time.Duration {
+	if neverExpires(li.ExpiresAt) {
+		return math.MaxInt64
+	}
+	clock := li.clock
+	if clock == nil {
+		clock = defaultClock
+	}
+	now := clock.Now().Truncate(time.Second)
+	if d := li.ExpiresAt.Truncate(time.Second).Sub(now); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// neverExpires returns true if the expiry time exp stands for no expiry,
+// i.e. is the zero time or the Unix epoch of a missing exp claim.
+func neverExpires(exp time.Time) bool {
+	return exp.IsZero() || exp.Unix() == 0
+}
+
+// IsLicenseInfoValid checks the validity period of li at now, without
+// verifying the license again, e.g. for license info cached after Verify. It
+// returns an error wrapping ErrNotYetValid before NotBefore and
+// ErrLicenseExpired from ExpiresAt on. Like Verify, times are compared with a
+// precision of one second and zero times don't restrict the period.
+func IsLicenseInfoValid(li LicenseInfo, now time.Time) error {
+	now = now.Truncate(time.Second)
+	if !li.NotBefore.IsZero() && now.Before(li.NotBefore.Truncate(time.Second)) {
+		return fmt.Errorf("%w: valid from %s", ErrNotYetValid, li.NotBefore)
+	}
+	if !li.ExpiresAt.IsZero() && li.ExpiresAt.Unix() != 0 && !now.Before(li.ExpiresAt.Truncate(time.Second)) {
+		return fmt.Errorf("%w: expired on %s", ErrLicenseExpired, li.ExpiresAt)
+	}
+	return nil
+}
+
+// HasFeature returns true if the license enables the feature name.
+func (li LicenseInfo) HasFeature(name string) bool {
+	return li.Features[name]
+}
+
+// IsTrialLicense returns true for trial licenses, i.e. licenses flagged as
+// trials or on the trial plan, matched ignoring case and surrounding spaces.
+func (li LicenseInfo) IsTrialLicense() bool {
+	if li.IsTrial {
+		return true
+	}
+	p, err := ParsePlan(li.Plan)
+	return err == nil && p == PlanTrial
+}
+
+// TrialDaysLeft returns the number of days left before a trial license
+// expires, counting a partial day as a whole one so that a trial expiring
+// today has one day left. It returns zero for expired trials and licenses
+// that aren't trials, and math.MaxInt for trials without expiry.
+func (li LicenseInfo) TrialDaysLeft() int {
+	if !li.IsTrialLicense() {
+		return 0
+	}
+	remaining := li.Remaining()
+	if remaining == math.MaxInt64 {
+		return math.MaxInt
+	}
+	const day = 24 * time.Hour
+	return int((remaining + day - 1) / day)
+}
+
+// CapacityBytes returns the storage capacity of the license in bytes, or
+// math.MaxInt64 if it doesn't fit in an int64. It returns zero for licenses
+// without storage capacity, which are unlimited. The exact capacity is
+// returned for capacities with a unit, e.g. 512GiB, rather than the rounded
+// StorageCapacity.
+func (li LicenseInfo) CapacityBytes() int64 {
+	if li.StorageCapacity == 0 {
+		return 0
+	}
+	if li.StorageBytes != 0 {
+		return li.StorageBytes
+	}
+	return Capacity(li.StorageCapacity).Bytes()
+}
+
+// storageBytes returns the value of LicenseInfo.StorageBytes for a capacity
+// of tb TB and n bytes: n, or zero if n is also the number of bytes of tb or
+// isn't known.
+func storageBytes(tb Capacity, n int64) int64 {
+	if n == tb.Bytes() {
+		return 0
+	}
+	return n
+}
+
+// WithinCapacity returns true if usedBytes doesn't exceed the storage
+// capacity of the license. Licenses without storage capacity are unlimited.
+func (li LicenseInfo) WithinCapacity(usedBytes int64) bool {
+	if li.StorageCapacity == 0 {
+		return true
+	}
+	return usedBytes <= li.CapacityBytes()
+}
+
+// Fingerprint returns a stable identifier of the license, the hex encoded
+// SHA-256 of its account ID, deployment ID and issue time, meant to correlate
+// reports of the same license without keeping the license key. It is a
+// deduplication key only: it doesn't prove that the license is genuine.
+func (li LicenseInfo) Fingerprint() string {
+	// Numbers are on both ends, so the deployment ID can't be confused with
+	// them whatever it contains.
+	sum := sha256.Sum256([]byte(strconv.FormatInt(li.AccountID, 10) + "|" + li.DeploymentID + "|" + strconv.FormatInt(li.IssuedAt.Unix(), 10)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Equal returns true if li and other carry the same license claims. Times
+// are compared with time.Time.Equal, so that the same instants in different
+// locations or with different monotonic clock readings are equal, and nil
+// maps are equal to empty ones. The license token and the raw values kept by
+// WithNormalizeStrings are ignored: they tell how the license info was
+// obtained rather than what it grants.
+func (li LicenseInfo) Equal(other LicenseInfo) bool {
+	return li.LicenseID == other.LicenseID &&
+		li.Email == other.Email &&
+		li.Subject == other.Subject &&
+		li.Organization == other.Organization &&
+		li.AccountID == other.AccountID &&
+		li.ParentAccountID == other.ParentAccountID &&
+		li.DeploymentID == other.DeploymentID &&
+		li.StorageCapacity == other.StorageCapacity &&
+		li.CapacityBytes() == other.CapacityBytes() &&
+		li.Cap == other.Cap &&
+		li.Plan == other.Plan &&
+		li.IssuedAt.Equal(other.IssuedAt) &&
+		li.ExpiresAt.Equal(other.ExpiresAt) &&
+		li.NotBefore.Equal(other.NotBefore) &&
+		li.APIKey == other.APIKey &&
+		li.IsTrial == other.IsTrial &&
+		li.Issuer == other.Issuer &&
+		li.MaxNodes == other.MaxNodes &&
+		maps.Equal(li.Features, other.Features) &&
+		(len(li.Extra) == 0 && len(other.Extra) == 0 || reflect.DeepEqual(li.Extra, other.Extra))
+}
+
+// MergeLicenseInfo returns the effective license of a deployment carrying
+// several licenses, e.g. a base license and add-ons:
+//
+//   - the storage capacities, in TB and in bytes, and the maximum numbers of
+//     nodes are summed; a license without limit, i.e. a zero value, makes
+//     the merged one unlimited too. Sums overflowing an int64 saturate at math.MaxInt64.
+//   - a feature is enabled if any license enables it, and disabled if some
+//     license disables it and none enables it.
+//   - the validity window is the one common to all licenses: ExpiresAt is
+//     the earliest expiry, NotBefore and IssuedAt the latest values. Licenses
+//     without expiry don't restrict it, the merged license never expires
+//     only if none of them expires.
+//   - all the other fields are those of the first license, except the
+//     license token which is left empty as the merged info doesn't come
+//     from a single license.
+//
+// All licenses must be issued to the same account and, if they have one, to
+// the same deployment; an error is returned otherwise or without licenses.
+func MergeLicenseInfo(infos ...LicenseInfo) (LicenseInfo, error) {
+	if len(infos) == 0 {
+		return LicenseInfo{}, errors.New("no license to merge")
+	}
+	merged := infos[0]
+	merged.LicenseToken = ""
+	merged.Features = nil
+	mergeLimit := func(a, b int64) int64 {
+		if a == 0 || b == 0 {
+			return 0
+		}
+		if a > math.MaxInt64-b {
+			return math.MaxInt64
+		}
+		return a + b
+	}
+	for i, li := range infos {
+		if li.AccountID != merged.AccountID {
+			return LicenseInfo{}, fmt.Errorf("license #%d is issued to account %d, not %d", i+1, li.AccountID, merged.AccountID)
+		}
+		if li.DeploymentID != "" {
+			if merged.DeploymentID == "" {
+				merged.DeploymentID = li.DeploymentID
+			} else if li.DeploymentID != merged.DeploymentID {
+				return LicenseInfo{}, fmt.Errorf("license #%d is issued to deployment %s, not %s", i+1, li.DeploymentID, merged.DeploymentID)
+			}
+		}
+		if i > 0 {
+			n := mergeLimit(merged.CapacityBytes(), li.CapacityBytes())
+			merged.StorageCapacity = mergeLimit(merged.StorageCapacity, li.StorageCapacity)
+			merged.StorageBytes = storageBytes(Capacity(merged.StorageCapacity), n)
+			merged.MaxNodes = mergeLimit(merged.MaxNodes, li.MaxNodes)
+			// A zero expiry means no expiry, it must not win over the
+			// expiry of another license.
+			if neverExpires(merged.ExpiresAt) || !neverExpires(li.ExpiresAt) && li.ExpiresAt.Before(merged.ExpiresAt) {
+				merged.ExpiresAt = li.ExpiresAt
+			}
+			if li.NotBefore.After(merged.NotBefore) {
+				merged.NotBefore = li.NotBefore
+			}
+			if li.IssuedAt.After(merged.IssuedAt) {
+				merged.IssuedAt = li.IssuedAt
+			}
+		}
+		for name, enabled := range li.Features {
+			if merged.Features == nil {
+				merged.Features = make(map[string]bool)
+			}
+			merged.Features[name] = merged.Features[name] || enabled
+		}
+	}
+	merged.Cap = Capacity(merged.StorageCapacity)
+	return merged, nil
+}
+
+// String returns a single line description of the license suitable for
+// logging, with fields in a fixed order. The license token and API key are
+// left out.
+func (li LicenseInfo) String() string {
+	return li.describe(li.Email)
+}
+
+// SafeString is like String but with the email redacted by RedactedEmail,
+// for logs shared with third parties.
+func (li LicenseInfo) SafeString() string {
+	return li.describe(li.RedactedEmail())
+}
+
+// RedactedEmail returns the email with its local part masked but for the
+// first character, e.g. j***@example.com. Emails without local part or
+// domain are masked entirely, an empty email stays empty.
+func (li LicenseInfo) RedactedEmail() string {
+	if li.Email == "" {
+		return ""
+	}
+	at := strings.LastIndexByte(li.Email, '@')
+	if at <= 0 || at == len(li.Email)-1 {
+		return "***"
+	}
+	_, size := utf8.DecodeRuneInString(li.Email)
+	return li.Email[:size] + "***" + li.Email[at:]
+}
+
+// describe returns the description of the license returned by String, with
+// email as email address.
+func (li LicenseInfo) describe(email string) string {
+	var sb strings.Builder
+	field := func(name, value string) {
+		if sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		if value == "" || strings.ContainsAny(value, " \t\"=") {
+			value = strconv.Quote(value)
+		}
+		sb.WriteString(name)
+		sb.WriteByte('=')
+		sb.WriteString(value)
+	}
+	field("id", li.LicenseID)
+	field("org", li.Organization)
+	field("email", email)
+	field("account", strconv.FormatInt(li.AccountID, 10))
+	field("plan", li.Plan)
+	field("cap", fmt.Sprintf("%dTB", li.StorageCapacity))
+	field("issued", li.IssuedAt.UTC().Format(time.DateOnly))
+	field("expires", li.ExpiresAt.UTC().Format(time.DateOnly))
+	field("deployment", li.DeploymentID)
+	return sb.String()
+}
+
+// subject returns the subject of the license, the email for license info
+// without subject, e.g. built by hand.
+func (li LicenseInfo) subject() string {
+	if li.Subject == "" {
+		return li.Email
+	}
+	return li.Subject
+}
+
+// licenseInfoJSON is the JSON representation of LicenseInfo. Fields are named
+// after the license claims, times are formatted as RFC3339.
+type licenseInfoJSON struct {
+	LicenseToken    string                 `json:"token"`
+	LicenseID       string                 `json:"lid"`
+	Subject         string                 `json:"sub"`
+	Organization    string                 `json:"org"`
+	AccountID       int64                  `json:"aid"`
+	ParentAccountID int64                  `json:"paid"`
+	DeploymentID    string                 `json:"did"`
+	StorageCapacity int64                  `json:"cap"`
+	StorageBytes    int64                  `json:"capBytes"`
+	Plan            string                 `json:"plan"`
+	IssuedAt        time.Time              `json:"iat"`
+	ExpiresAt       time.Time              `json:"exp"`
+	NotBefore       time.Time              `json:"nbf"`
+	APIKey          string                 `json:"apiKey"`
+	IsTrial         bool                   `json:"trial"`
+	Issuer          string                 `json:"iss"`
+	MaxNodes        int64                  `json:"nodes"`
+	Features        map[string]bool        `json:"features"`
+	Extra           map[string]interface{} `json:"extra"`
+	Raw             map[string]string      `json:"raw"`
+}
+
+// MarshalJSON returns the JSON encoding of the license info, using the claim
+// names of the license key as field names.
+//
+// So that the encoding round-trips, it carries the secrets of the license:
+// the license token as "token" and the API key as "apiKey". It must be
+// stored like the license itself; use ToMap for a representation without
+// them, e.g. for logs.
+func (li LicenseInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(licenseInfoJSON{
+		LicenseToken:    li.LicenseToken,
+		LicenseID:       li.LicenseID,
+		Subject:         li.subject(),
+		Organization:    li.Organization,
+		AccountID:       li.AccountID,
+		ParentAccountID: li.ParentAccountID,
+		DeploymentID:    li.DeploymentID,
+		StorageCapacity: li.StorageCapacity,
+		StorageBytes:    li.CapacityBytes(),
+		Plan:            li.Plan,
+		IssuedAt:        li.IssuedAt,
+		ExpiresAt:       li.ExpiresAt,
+		NotBefore:       li.NotBefore,
+		APIKey:          li.APIKey,
+		IsTrial:         li.IsTrial,
+		Issuer:          li.Issuer,
+		MaxNodes:        li.MaxNodes,
+		Features:        li.Features,
+		Extra:           li.Extra,
+		Raw:             li.Raw,
+	})
+}
+
+// ToMap returns the license info as a map with the keys of MarshalJSON and
+// values of primitive types only, e.g. to build a protobuf Struct: strings,
+// bools and int64 numbers, times as RFC3339 strings in UTC, empty for zero
+// times, and nested maps of type map[string]interface{}. All keys are always
+// present. The extra claims keep their JSON decoded values, slices being of
+// type []interface{}.
+//
+// The license token and the API key are secrets, which the map doesn't
+// have: use ToMapWithSecrets to include them.
+func (li LicenseInfo) ToMap() map[string]interface{} {
+	timeString := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+	features := make(map[string]interface{}, len(li.Features))
+	for name, enabled := range li.Features {
+		features[name] = enabled
+	}
+	extra := make(map[string]interface{}, len(li.Extra))
+	for name, v := range li.Extra {
+		if s, ok := v.([]string); ok {
+			l := make([]interface{}, len(s))
+			for i := range s {
+				l[i] = s[i]
+			}
+			v = l
+		}
+		extra[name] = v
+	}
+	raw := make(map[string]interface{}, len(li.Raw))
+	for name, v := range li.Raw {
+		raw[name] = v
+	}
+	return map[string]interface{}{
+		"lid":      li.LicenseID,
+		"sub":      li.subject(),
+		"org":      li.Organization,
+		"aid":      li.AccountID,
+		"paid":     li.ParentAccountID,
+		"did":      li.DeploymentID,
+		"cap":      li.StorageCapacity,
+		"capBytes": li.CapacityBytes(),
+		"plan":     li.Plan,
+		"iat":      timeString(li.IssuedAt),
+		"exp":      timeString(li.ExpiresAt),
+		"nbf":      timeString(li.NotBefore),
+		"trial":    li.IsTrial,
+		"iss":      li.Issuer,
+		"nodes":    li.MaxNodes,
+		"features": features,
+		"extra":    extra,
+		"raw":      raw,
+	}
+}
+
+// ToMapWithSecrets is like ToMap but the map also has the license token and
+// the API key, with the keys "token" and "apiKey". The map must be handled
+// like the license itself, e.g. not logged.
+func (li LicenseInfo) ToMapWithSecrets() map[string]interface{} {
+	m := li.ToMap()
+	m["token"] = li.LicenseToken
+	m[apiKey] = li.APIKey
+	return m
+}
+
+// UnmarshalJSON decodes license info encoded by MarshalJSON.
+func (li *LicenseInfo) UnmarshalJSON(data []byte) error {
+	var v licenseInfoJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	var email string
+	if isEmail(v.Subject) {
+		email = v.Subject
+	}
+	*li = LicenseInfo{
+		LicenseToken:    v.LicenseToken,
+		LicenseID:       v.LicenseID,
+		Email:           email,
+		Subject:         v.Subject,
+		Organization:    v.Organization,
+		AccountID:       v.AccountID,
+		ParentAccountID: v.ParentAccountID,
+		DeploymentID:    v.DeploymentID,
+		StorageCapacity: v.StorageCapacity,
+		StorageBytes:    storageBytes(Capacity(v.StorageCapacity), v.StorageBytes),
+		Plan:            v.Plan,
+		IssuedAt:        v.IssuedAt,
+		ExpiresAt:       v.ExpiresAt,
+		NotBefore:       v.NotBefore,
+		APIKey:          v.APIKey,
+		IsTrial:         v.IsTrial,
+		Issuer:          v.Issuer,
+		MaxNodes:        v.MaxNodes,
+		Features:        v.Features,
+		Extra:           v.Extra,
+		Raw:             v.Raw,
+		Cap:             Capacity(v.StorageCapacity),
+	}
+	return nil
+}
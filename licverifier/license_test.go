@@ -0,0 +1,532 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// setTestClock fixes the package clock at now for the duration of the test.
+func setTestClock(t *testing.T, now time.Time) {
+	t.Helper()
+	saved := defaultClock
+	defaultClock = jwt.ClockFunc(func() time.Time { return now })
+	t.Cleanup(func() { defaultClock = saved })
+}
+
+func TestLicenseInfoRemaining(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	setTestClock(t, now)
+
+	testCases := []struct {
+		expiresAt time.Time
+		remaining time.Duration
+		expired   bool
+	}{
+		{now.Add(time.Hour), time.Hour, false},
+		{now.Add(time.Second), time.Second, false},
+		{now.Add(500 * time.Millisecond), 0, true},
+		{now, 0, true},
+		{now.Add(-time.Hour), 0, true},
+		{time.Time{}, math.MaxInt64, false},
+	}
+	for i, tc := range testCases {
+		li := LicenseInfo{ExpiresAt: tc.expiresAt}
+		if got := li.Remaining(); got != tc.remaining {
+			t.Errorf("%d: Expected remaining %s but got %s", i+1, tc.remaining, got)
+		}
+		if got := li.IsExpired(); got != tc.expired {
+			t.Errorf("%d: Expected expired %v but got %v", i+1, tc.expired, got)
+		}
+	}
+}
+
+func TestIsLicenseInfoValid(t *testing.T) {
+	nbf := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	exp := nbf.Add(24 * time.Hour)
+	li := LicenseInfo{NotBefore: nbf, ExpiresAt: exp}
+
+	testCases := []struct {
+		li          LicenseInfo
+		now         time.Time
+		expectedErr error
+	}{
+		{li, nbf.Add(-time.Second), ErrNotYetValid},
+		{li, nbf.Add(-time.Millisecond), ErrNotYetValid},
+		{li, nbf, nil},
+		{li, nbf.Add(time.Hour), nil},
+		{li, exp.Add(-time.Second), nil},
+		{li, exp, ErrLicenseExpired},
+		{li, exp.Add(500 * time.Millisecond), ErrLicenseExpired},
+		{li, exp.Add(time.Hour), ErrLicenseExpired},
+		{LicenseInfo{ExpiresAt: exp}, time.Time{}, nil},
+		{LicenseInfo{NotBefore: nbf}, nbf.Add(100 * 365 * 24 * time.Hour), nil},
+		{LicenseInfo{ExpiresAt: time.Unix(0, 0)}, exp, nil},
+		{LicenseInfo{}, exp, nil},
+	}
+	for i, tc := range testCases {
+		err := IsLicenseInfoValid(tc.li, tc.now)
+		if !errors.Is(err, tc.expectedErr) || (tc.expectedErr == nil && err != nil) {
+			t.Errorf("%d: Expected error %v but got %v", i+1, tc.expectedErr, err)
+		}
+	}
+}
+
+func TestLicenseInfoTrial(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	setTestClock(t, now)
+	day := 24 * time.Hour
+
+	testCases := []struct {
+		li       LicenseInfo
+		trial    bool
+		daysLeft int
+	}{
+		{LicenseInfo{Plan: "TRIAL", ExpiresAt: now.Add(14 * day)}, true, 14},
+		{LicenseInfo{Plan: " trial ", ExpiresAt: now.Add(13*day + time.Hour)}, true, 14},
+		{LicenseInfo{Plan: "Trial", ExpiresAt: now.Add(time.Minute)}, true, 1},
+		{LicenseInfo{Plan: "TRIAL", ExpiresAt: now.Add(-time.Hour)}, true, 0},
+		{LicenseInfo{Plan: "TRIAL"}, true, math.MaxInt},
+		{LicenseInfo{Plan: "STANDARD", IsTrial: true, ExpiresAt: now.Add(day)}, true, 1},
+		{LicenseInfo{Plan: "ENTERPRISE", ExpiresAt: now.Add(day)}, false, 0},
+		{LicenseInfo{Plan: "TRIALS", ExpiresAt: now.Add(day)}, false, 0},
+		{LicenseInfo{ExpiresAt: now.Add(day)}, false, 0},
+	}
+	for i, tc := range testCases {
+		if got := tc.li.IsTrialLicense(); got != tc.trial {
+			t.Errorf("%d: Expected trial %v but got %v", i+1, tc.trial, got)
+		}
+		if got := tc.li.TrialDaysLeft(); got != tc.daysLeft {
+			t.Errorf("%d: Expected %d days left but got %d", i+1, tc.daysLeft, got)
+		}
+	}
+}
+
+func TestLicenseInfoCapacity(t *testing.T) {
+	testCases := []struct {
+		capacity      int64
+		usedBytes     int64
+		capacityBytes int64
+		within        bool
+	}{
+		{0, math.MaxInt64, 0, true},
+		{1, 1_000_000_000_000, 1_000_000_000_000, true},
+		{1, 1_000_000_000_001, 1_000_000_000_000, false},
+		{500, 0, 500_000_000_000_000, true},
+		{math.MaxInt64 / 1_000_000_000_000, math.MaxInt64, 9_223_372_000_000_000_000, false},
+		{math.MaxInt64/1_000_000_000_000 + 1, math.MaxInt64, math.MaxInt64, true},
+		{math.MaxInt64, math.MaxInt64, math.MaxInt64, true},
+	}
+	for i, tc := range testCases {
+		li := LicenseInfo{StorageCapacity: tc.capacity}
+		if got := li.CapacityBytes(); got != tc.capacityBytes {
+			t.Errorf("%d: Expected %d bytes but got %d", i+1, tc.capacityBytes, got)
+		}
+		if got := li.WithinCapacity(tc.usedBytes); got != tc.within {
+			t.Errorf("%d: Expected within capacity %v but got %v", i+1, tc.within, got)
+		}
+	}
+}
+
+func TestLicenseInfoFingerprint(t *testing.T) {
+	iat := time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC)
+	li := LicenseInfo{AccountID: 42, DeploymentID: "abc123", IssuedAt: iat, Organization: "Acme"}
+	const expected = "0a7448779df913d2ca5d9b7836e3c4c383ec78d3f3d6bd27269725202daa44f5"
+	if got := li.Fingerprint(); got != expected {
+		t.Fatalf("Expected fingerprint %s but got %s", expected, got)
+	}
+
+	// Only the account, deployment and issue time are used.
+	same := LicenseInfo{AccountID: 42, DeploymentID: "abc123", IssuedAt: iat.In(time.FixedZone("CET", 3600)), Plan: "ENTERPRISE"}
+	if got := same.Fingerprint(); got != expected {
+		t.Fatalf("Expected fingerprint %s but got %s", expected, got)
+	}
+	for i, other := range []LicenseInfo{
+		{AccountID: 43, DeploymentID: "abc123", IssuedAt: iat},
+		{AccountID: 42, DeploymentID: "abc124", IssuedAt: iat},
+		{AccountID: 42, DeploymentID: "abc123", IssuedAt: iat.Add(time.Second)},
+		{AccountID: 42, DeploymentID: "abc123"},
+	} {
+		if other.Fingerprint() == expected {
+			t.Fatalf("%d: Expected a different fingerprint than %s", i+1, expected)
+		}
+	}
+}
+
+func TestLicenseInfoEqual(t *testing.T) {
+	exp := time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC)
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		paris = time.FixedZone("CET", 3600)
+	}
+	li := LicenseInfo{
+		LicenseToken:    "token",
+		LicenseID:       "lic-1",
+		Email:           "jane@example.com",
+		Organization:    "Acme",
+		AccountID:       42,
+		ParentAccountID: 7,
+		DeploymentID:    "abc123",
+		StorageCapacity: 100,
+		Cap:             100,
+		Plan:            "ENTERPRISE",
+		IssuedAt:        exp.Add(-365 * 24 * time.Hour),
+		ExpiresAt:       exp,
+		MaxNodes:        16,
+		Features:        map[string]bool{"replication": true},
+		Extra:           map[string]interface{}{"region": "eu-west-1"},
+	}
+
+	testCases := []struct {
+		update func(*LicenseInfo)
+		equal  bool
+	}{
+		{func(*LicenseInfo) {}, true},
+		// same instants elsewhere or with a monotonic clock reading
+		{func(o *LicenseInfo) { o.ExpiresAt = exp.In(paris) }, true},
+		{func(o *LicenseInfo) { o.IssuedAt = o.IssuedAt.Local() }, true},
+		{func(o *LicenseInfo) { o.NotBefore = time.Now().Add(time.Hour); li.NotBefore = o.NotBefore.Round(0) }, true},
+		// ignored fields
+		{func(o *LicenseInfo) { o.LicenseToken = "other" }, true},
+		{func(o *LicenseInfo) { o.Raw = map[string]string{"sub": "Jane@example.com"} }, true},
+		{func(o *LicenseInfo) { li.Features, o.Features = nil, map[string]bool{} }, true},
+		{func(o *LicenseInfo) { li.Extra, o.Extra = map[string]interface{}{}, nil }, true},
+		// differences
+		{func(o *LicenseInfo) { o.ExpiresAt = exp.Add(time.Second) }, false},
+		{func(o *LicenseInfo) { o.AccountID = 43 }, false},
+		{func(o *LicenseInfo) { o.ParentAccountID = 0 }, false},
+		{func(o *LicenseInfo) { o.Plan = "STANDARD" }, false},
+		{func(o *LicenseInfo) { o.Cap = 10 }, false},
+		{func(o *LicenseInfo) { o.Features = map[string]bool{"replication": false} }, false},
+		{func(o *LicenseInfo) { o.Extra = map[string]interface{}{"region": "us-east-1"} }, false},
+		{func(o *LicenseInfo) { o.Extra = nil }, false},
+		{func(o *LicenseInfo) { o.IsTrial = true }, false},
+	}
+	for i, tc := range testCases {
+		saved := li
+		other := li
+		other.Features = maps.Clone(li.Features)
+		other.Extra = maps.Clone(li.Extra)
+		tc.update(&other)
+		if got := li.Equal(other); got != tc.equal {
+			t.Errorf("%d: Expected equal %v but got %v", i+1, tc.equal, got)
+		}
+		if got := other.Equal(li); got != tc.equal {
+			t.Errorf("%d: Expected symmetric equal %v but got %v", i+1, tc.equal, got)
+		}
+		li = saved
+	}
+}
+
+func TestLicenseInfoString(t *testing.T) {
+	li := LicenseInfo{
+		LicenseToken:    "token",
+		LicenseID:       "lic-1",
+		Email:           "jane@example.com",
+		Organization:    "Acme",
+		AccountID:       42,
+		DeploymentID:    "abc123",
+		StorageCapacity: 500,
+		Plan:            "ENTERPRISE",
+		IssuedAt:        time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC),
+		ExpiresAt:       time.Date(2025, time.January, 2, 3, 4, 5, 0, time.UTC),
+		APIKey:          "secret",
+	}
+	expected := "id=lic-1 org=Acme email=jane@example.com account=42 plan=ENTERPRISE cap=500TB issued=2024-01-02 expires=2025-01-02 deployment=abc123"
+	if got := li.String(); got != expected {
+		t.Fatalf("Expected %s but got %s", expected, got)
+	}
+
+	li.Organization = "Gringotts Inc."
+	li.DeploymentID = ""
+	expected = `id=lic-1 org="Gringotts Inc." email=jane@example.com account=42 plan=ENTERPRISE cap=500TB issued=2024-01-02 expires=2025-01-02 deployment=""`
+	if got := li.String(); got != expected {
+		t.Fatalf("Expected %s but got %s", expected, got)
+	}
+}
+
+func TestLicenseInfoRedactedEmail(t *testing.T) {
+	testCases := []struct {
+		email    string
+		expected string
+	}{
+		{"jane@example.com", "j***@example.com"},
+		{"j@example.com", "j***@example.com"},
+		{"élodie@example.fr", "é***@example.fr"},
+		{"jane@team@example.com", "j***@example.com"},
+		{"jane", "***"},
+		{"@example.com", "***"},
+		{"jane@", "***"},
+		{"", ""},
+	}
+	for i, tc := range testCases {
+		if got := (LicenseInfo{Email: tc.email}).RedactedEmail(); got != tc.expected {
+			t.Fatalf("%d: Expected %s but got %s", i+1, tc.expected, got)
+		}
+	}
+
+	li := LicenseInfo{
+		LicenseID:       "lic-1",
+		Email:           "jane@example.com",
+		Organization:    "Acme",
+		AccountID:       42,
+		DeploymentID:    "abc123",
+		StorageCapacity: 500,
+		Plan:            "ENTERPRISE",
+		IssuedAt:        time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC),
+		ExpiresAt:       time.Date(2025, time.January, 2, 3, 4, 5, 0, time.UTC),
+	}
+	expected := "id=lic-1 org=Acme email=j***@example.com account=42 plan=ENTERPRISE cap=500TB issued=2024-01-02 expires=2025-01-02 deployment=abc123"
+	if got := li.SafeString(); got != expected {
+		t.Fatalf("Expected %s but got %s", expected, got)
+	}
+}
+
+func TestMergeLicenseInfo(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	base := LicenseInfo{
+		LicenseToken:    "base-token",
+		LicenseID:       "base",
+		Organization:    "Acme",
+		AccountID:       42,
+		DeploymentID:    "abc123",
+		StorageCapacity: 100,
+		Cap:             100,
+		Plan:            "ENTERPRISE",
+		IssuedAt:        now.Add(-30 * 24 * time.Hour),
+		ExpiresAt:       now.Add(365 * 24 * time.Hour),
+		MaxNodes:        8,
+		Features:        map[string]bool{"replication": true, "tiering": false},
+	}
+	addOn := LicenseInfo{
+		LicenseToken:    "add-on-token",
+		LicenseID:       "add-on",
+		Organization:    "Acme",
+		AccountID:       42,
+		StorageCapacity: 50,
+		Cap:             50,
+		Plan:            "ENTERPRISE",
+		IssuedAt:        now.Add(-24 * time.Hour),
+		NotBefore:       now,
+		ExpiresAt:       now.Add(90 * 24 * time.Hour),
+		MaxNodes:        4,
+		Features:        map[string]bool{"tiering": true, "kms": false},
+	}
+
+	merged, err := MergeLicenseInfo(base, addOn)
+	if err != nil {
+		t.Fatalf("Failed to merge licenses: %s", err)
+	}
+	expected := base
+	expected.LicenseToken = ""
+	expected.StorageCapacity, expected.Cap, expected.MaxNodes = 150, 150, 12
+	expected.IssuedAt, expected.NotBefore, expected.ExpiresAt = addOn.IssuedAt, addOn.NotBefore, addOn.ExpiresAt
+	expected.Features = map[string]bool{"replication": true, "tiering": true, "kms": false}
+	if !reflect.DeepEqual(expected, merged) {
+		t.Fatalf("Expected %#v but got %#v", expected, merged)
+	}
+	if base.Features["tiering"] {
+		t.Fatal("Expected the features of the merged licenses to be left unchanged")
+	}
+	if merged, err = MergeLicenseInfo(base); err != nil || merged.StorageCapacity != 100 || merged.LicenseToken != "" {
+		t.Fatalf("Expected a single license to merge into itself, got %v and %v", merged, err)
+	}
+
+	unlimited := addOn
+	unlimited.StorageCapacity, unlimited.Cap, unlimited.MaxNodes = 0, 0, 0
+	if merged, err = MergeLicenseInfo(base, unlimited); err != nil || merged.StorageCapacity != 0 || merged.MaxNodes != 0 {
+		t.Fatalf("Expected unlimited merged license, got %v and %v", merged, err)
+	}
+
+	expiring := addOn
+	expiring.ExpiresAt = now.Add(time.Hour)
+	for i, infos := range [][]LicenseInfo{
+		{expiring, {AccountID: 42}},
+		{{AccountID: 42}, expiring},
+		{{AccountID: 42, ExpiresAt: time.Unix(0, 0)}, expiring},
+		{{AccountID: 42}, expiring, {AccountID: 42, ExpiresAt: time.Unix(0, 0)}},
+	} {
+		if merged, err = MergeLicenseInfo(infos...); err != nil {
+			t.Fatalf("%d: Failed to merge licenses: %s", i+1, err)
+		}
+		if !merged.ExpiresAt.Equal(expiring.ExpiresAt) {
+			t.Fatalf("%d: Expected merged license to expire on %s, got %s", i+1, expiring.ExpiresAt, merged.ExpiresAt)
+		}
+	}
+	if merged, err = MergeLicenseInfo(LicenseInfo{AccountID: 42}, LicenseInfo{AccountID: 42}); err != nil || merged.Remaining() != math.MaxInt64 {
+		t.Fatalf("Expected merged license without expiry, got %v and %v", merged, err)
+	}
+
+	for i, infos := range [][]LicenseInfo{
+		nil,
+		{base, {AccountID: 43}},
+		{base, {AccountID: 42, DeploymentID: "def456"}},
+		{addOn, {AccountID: 42, DeploymentID: "abc123"}, base, {AccountID: 42, DeploymentID: "def456"}},
+	} {
+		if _, err = MergeLicenseInfo(infos...); err == nil {
+			t.Fatalf("%d: Expected merge to fail", i+1)
+		}
+	}
+}
+
+func TestLicenseInfoToMap(t *testing.T) {
+	priv := newTestECKey(t)
+	lv, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	exp := time.Now().Add(time.Hour).Truncate(time.Second)
+	li, err := lv.Verify(signTestLicense(t, jwa.ES384, priv, map[string]interface{}{
+		jwt.ExpirationKey: exp,
+		jwt.AudienceKey:   []string{"minio"},
+		deploymentID:      "abc123",
+		features:          map[string]bool{"replication": true},
+		"region":          "eu-west-1",
+	}))
+	if err != nil {
+		t.Fatalf("Expected license to pass verification but failed with %s", err)
+	}
+
+	m := li.ToMap()
+	expected := map[string]interface{}{
+		"lid":      "",
+		"sub":      "jane@example.com",
+		"org":      "Example Inc.",
+		"aid":      int64(1),
+		"paid":     int64(0),
+		"did":      "abc123",
+		"cap":      int64(50),
+		"capBytes": int64(50e12),
+		"plan":     "STANDARD",
+		"iat":      li.IssuedAt.UTC().Format(time.RFC3339),
+		"exp":      exp.UTC().Format(time.RFC3339),
+		"nbf":      "",
+		"trial":    false,
+		"iss":      "",
+		"nodes":    int64(0),
+		"features": map[string]interface{}{"replication": true},
+		"extra":    map[string]interface{}{"region": "eu-west-1", "aud": []interface{}{"minio"}},
+		"raw":      map[string]interface{}{},
+	}
+	if !reflect.DeepEqual(expected, m) {
+		t.Fatalf("Expected %#v but got %#v", expected, m)
+	}
+
+	// All keys are present with the same types for empty license info.
+	empty := LicenseInfo{}.ToMap()
+	for key, v := range m {
+		e, ok := empty[key]
+		if !ok {
+			t.Fatalf("Expected key %s in %v", key, empty)
+		}
+		if reflect.TypeOf(e) != reflect.TypeOf(v) {
+			t.Fatalf("Expected %s of type %T but got %T", key, v, e)
+		}
+	}
+	if len(empty) != len(m) {
+		t.Fatalf("Expected %d keys but got %d", len(m), len(empty))
+	}
+
+	// The secrets are only in the map of ToMapWithSecrets.
+	li.APIKey = "secret-api-key"
+	if m = li.ToMap(); m["token"] != nil || m["apiKey"] != nil {
+		t.Fatalf("Expected no secrets in %v", m)
+	}
+	m = li.ToMapWithSecrets()
+	if m["token"] != li.LicenseToken || m["apiKey"] != "secret-api-key" || len(m) != len(expected)+2 {
+		t.Fatalf("Expected the license token and API key in %v", m)
+	}
+}
+
+func TestLicenseInfoJSON(t *testing.T) {
+	testCases := []LicenseInfo{
+		{
+			LicenseToken:    "token",
+			LicenseID:       "00000000-0000-0000-0000-000000000001",
+			Email:           "jane@example.com",
+			Subject:         "jane@example.com",
+			Organization:    "Acme",
+			AccountID:       42,
+			DeploymentID:    "abc123",
+			StorageCapacity: 500,
+			Cap:             500,
+			Plan:            "ENTERPRISE",
+			IssuedAt:        time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC),
+			ExpiresAt:       time.Date(2025, time.January, 2, 3, 4, 5, 0, time.UTC),
+			NotBefore:       time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC),
+			APIKey:          "secret",
+			IsTrial:         true,
+			Issuer:          "subnet@min.io",
+			MaxNodes:        16,
+			Features:        map[string]bool{"replication": true, "tiering": false},
+			Extra:           map[string]interface{}{"region": "eu-west-1"},
+			Raw:             map[string]string{"org": "Acme"},
+		},
+		{
+			Subject:      "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+			Organization: "Acme",
+			Plan:         "STANDARD",
+		},
+		{
+			Organization: "Acme",
+			Plan:         "TRIAL",
+		},
+	}
+	for i, li := range testCases {
+		data, err := json.Marshal(li)
+		if err != nil {
+			t.Fatalf("%d: Failed to marshal license info: %s", i+1, err)
+		}
+		for _, key := range []string{`"aid":`, `"did":`, `"cap":`, `"exp":`} {
+			if !strings.Contains(string(data), key) {
+				t.Fatalf("%d: Expected %s in %s", i+1, key, data)
+			}
+		}
+		var got LicenseInfo
+		if err = json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("%d: Failed to unmarshal license info: %s", i+1, err)
+		}
+		if !reflect.DeepEqual(li, got) {
+			t.Fatalf("%d: Expected %#v but got %#v", i+1, li, got)
+		}
+	}
+
+	data, err := json.Marshal(testCases[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"exp":"2025-01-02T03:04:05Z"`) {
+		t.Fatalf("Expected RFC3339 expiry in %s", data)
+	}
+	// The secrets are part of the encoding.
+	for _, field := range []string{`"token":"token"`, `"apiKey":"secret"`} {
+		if !strings.Contains(string(data), field) {
+			t.Fatalf("Expected %s in %s", field, data)
+		}
+	}
+}
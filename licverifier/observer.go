@@ -0,0 +1,90 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// Results of a license verification reported to an Observer. Failures are
+// categorized after the error they wrap.
+const (
+	ResultValid                = "valid"
+	ResultGracePeriod          = "grace_period"          // ErrInGracePeriod
+	ResultInvalidSignature     = "invalid_signature"     // ErrInvalidSignature
+	ResultUnsupportedAlgorithm = "unsupported_algorithm" // ErrUnsupportedAlgorithm
+	ResultExpired              = "expired"               // ErrLicenseExpired
+	ResultNotYetValid          = "not_yet_valid"         // ErrNotYetValid
+	ResultInvalidIssuer        = "invalid_issuer"        // ErrInvalidIssuer
+	ResultRevoked              = "revoked"               // ErrLicenseRevoked
+	ResultAccountNotAllowed    = "account_not_allowed"   // ErrAccountNotAllowed
+	ResultPlanNotAllowed       = "plan_not_allowed"      // ErrPlanNotAllowed
+	ResultCapacityExceeded     = "capacity_exceeded"     // ErrCapacityExceeded
+	ResultMalformedClaims      = "malformed_claims"      // ErrMalformedClaims
+	ResultCanceled             = "canceled"              // context canceled or deadline exceeded
+	ResultError                = "error"                 // any other error
+)
+
+// Observer is notified of the outcome of license verifications, e.g. to
+// export metrics. OnVerify is called with one of the Result constants and
+// the time the verification took. It may be called concurrently.
+type Observer interface {
+	OnVerify(result string, d time.Duration)
+}
+
+// WithObserver makes Verify report its outcome to o.
+func WithObserver(o Observer) jwt.ParseOption {
+	return newVerifyOption(func(vo *verifyOptions) {
+		vo.observer = o
+	})
+}
+
+// verifyResult returns the Result constant matching the error returned by
+// Verify.
+func verifyResult(err error) string {
+	if err == nil {
+		return ResultValid
+	}
+	for _, r := range []struct {
+		err    error
+		result string
+	}{
+		{ErrInGracePeriod, ResultGracePeriod},
+		{ErrInvalidSignature, ResultInvalidSignature},
+		{ErrUnsupportedAlgorithm, ResultUnsupportedAlgorithm},
+		{ErrLicenseExpired, ResultExpired},
+		{ErrNotYetValid, ResultNotYetValid},
+		{ErrInvalidIssuer, ResultInvalidIssuer},
+		{ErrLicenseRevoked, ResultRevoked},
+		{ErrAccountNotAllowed, ResultAccountNotAllowed},
+		{ErrPlanNotAllowed, ResultPlanNotAllowed},
+		{ErrCapacityExceeded, ResultCapacityExceeded},
+		{ErrMalformedClaims, ResultMalformedClaims},
+		{context.Canceled, ResultCanceled},
+		{context.DeadlineExceeded, ResultCanceled},
+	} {
+		if errors.Is(err, r.err) {
+			return r.result
+		}
+	}
+	return ResultError
+}
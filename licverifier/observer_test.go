@@ -0,0 +1,83 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+type testObserver struct {
+	mu      sync.Mutex
+	results []string
+}
+
+func (o *testObserver) OnVerify(result string, d time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if d < 0 {
+		result = "negative duration"
+	}
+	o.results = append(o.results, result)
+}
+
+func TestWithObserver(t *testing.T) {
+	priv := newTestECKey(t)
+	lv, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	expired := signTestLicense(t, jwa.ES384, priv, map[string]interface{}{jwt.ExpirationKey: time.Now().Add(-time.Hour)})
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	testCases := []struct {
+		ctx     context.Context
+		lic     string
+		options []jwt.ParseOption
+		result  string
+	}{
+		{context.Background(), signTestLicense(t, jwa.ES384, priv, nil), nil, ResultValid},
+		{context.Background(), expired, []jwt.ParseOption{WithGracePeriod(2 * time.Hour)}, ResultGracePeriod},
+		{context.Background(), "not-a-license", nil, ResultInvalidSignature},
+		{context.Background(), expired, nil, ResultExpired},
+		{context.Background(), signTestLicense(t, jwa.ES384, priv, map[string]interface{}{jwt.NotBeforeKey: time.Now().Add(time.Hour)}), nil, ResultNotYetValid},
+		{context.Background(), signTestLicense(t, jwa.ES384, priv, nil), []jwt.ParseOption{WithExpectedIssuer("subnet")}, ResultInvalidIssuer},
+		{context.Background(), signTestLicense(t, jwa.ES384, priv, map[string]interface{}{deploymentID: "abc123"}), []jwt.ParseOption{WithRevocationList(NewRevocationList("abc123"))}, ResultRevoked},
+		{context.Background(), signTestLicense(t, jwa.ES384, priv, nil), []jwt.ParseOption{WithAllowedAccountIDs(42)}, ResultAccountNotAllowed},
+		{context.Background(), signTestLicense(t, jwa.ES384, priv, map[string]interface{}{organization: nil}), nil, ResultMalformedClaims},
+		{canceled, signTestLicense(t, jwa.ES384, priv, nil), nil, ResultCanceled},
+	}
+	for i, tc := range testCases {
+		o := &testObserver{}
+		lv.VerifyContext(tc.ctx, tc.lic, append(tc.options, WithObserver(o))...)
+		if len(o.results) != 1 || o.results[0] != tc.result {
+			t.Fatalf("%d: Expected result %s but got %v", i+1, tc.result, o.results)
+		}
+	}
+
+	if got := verifyResult(errors.New("unexpected")); got != ResultError {
+		t.Fatalf("Expected result %s but got %s", ResultError, got)
+	}
+}
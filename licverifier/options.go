@@ -0,0 +1,273 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"encoding/asn1"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// verifyOptions holds the licverifier specific settings passed to Verify.
+type verifyOptions struct {
+	gracePeriod  time.Duration
+	ignoreExpiry bool
+	clock        jwt.Clock
+	strictPlan   bool
+	issuer       string
+	normalize    bool
+	planDefaults PlanDefaults
+
+	requiredClaims      []string
+	requireDeploymentID bool
+
+	expiryWarning   time.Duration
+	onExpiryWarning func(LicenseInfo, time.Duration)
+
+	revocationList   *RevocationList
+	accountIDs       map[int64]struct{}
+	parentAccountIDs map[int64]struct{}
+	plans            map[Plan]struct{}
+	usedBytes        int64
+	checkUsage       bool
+	validators       []func(LicenseInfo) error
+	cache            *VerifyCache
+	observer         Observer
+	certExtensionOID asn1.ObjectIdentifier
+
+	// maps of the license info reused by VerifyInto, nil to allocate them
+	features map[string]bool
+	extra    map[string]interface{}
+}
+
+// needsLicenseInfo returns true if vo has settings checking the license info
+// rather than the claims of a license, e.g. WithAllowedPlans.
+func (vo verifyOptions) needsLicenseInfo() bool {
+	return vo.onExpiryWarning != nil || vo.revocationList != nil || vo.accountIDs != nil ||
+		vo.parentAccountIDs != nil || vo.plans != nil || vo.checkUsage || len(vo.validators) > 0
+}
+
+// verifyOption is a jwt.ParseOption carrying a licverifier specific setting,
+// so that it can be passed to Verify along with the jwt options. Verify
+// removes them before handing the remaining options to jwt.
+type verifyOption struct {
+	jwt.ParseOption
+	apply func(*verifyOptions)
+}
+
+type identVerifyOption struct{}
+
+func (o *verifyOption) Ident() interface{} { return identVerifyOption{} }
+
+func (o *verifyOption) Value() interface{} { return o.apply }
+
+func newVerifyOption(apply func(*verifyOptions)) jwt.ParseOption {
+	return &verifyOption{apply: apply}
+}
+
+// splitOptions separates the licverifier specific options from the jwt ones.
+func splitOptions(options []jwt.ParseOption) (verifyOptions, []jwt.ParseOption) {
+	var vo verifyOptions
+	jwtOpts := make([]jwt.ParseOption, 0, len(options))
+	for _, o := range options {
+		if o, ok := o.(*verifyOption); ok {
+			o.apply(&vo)
+			continue
+		}
+		jwtOpts = append(jwtOpts, o)
+	}
+	return vo, jwtOpts
+}
+
+// WithGracePeriod makes Verify accept licenses that expired less than d ago.
+// For such licenses, Verify returns the license info along with an error
+// wrapping ErrInGracePeriod, so that callers can warn without blocking.
+func WithGracePeriod(d time.Duration) jwt.ParseOption {
+	return newVerifyOption(func(o *verifyOptions) {
+		o.gracePeriod = d
+	})
+}
+
+// WithClock sets the clock used by Verify to validate the license times. The
+// license info returned by Verify uses the same clock in IsExpired and
+// Remaining. It defaults to the system clock.
+func WithClock(clock jwt.Clock) jwt.ParseOption {
+	return newVerifyOption(func(o *verifyOptions) {
+		o.clock = clock
+	})
+}
+
+// WithStrictPlan makes Verify reject licenses whose plan isn't one of the
+// known plans with ErrMalformedClaims.
+func WithStrictPlan() jwt.ParseOption {
+	return newVerifyOption(func(o *verifyOptions) {
+		o.strictPlan = true
+	})
+}
+
+// WithExpectedIssuer makes Verify reject licenses whose iss claim isn't iss
+// with ErrInvalidIssuer.
+func WithExpectedIssuer(iss string) jwt.ParseOption {
+	return newVerifyOption(func(o *verifyOptions) {
+		o.issuer = iss
+	})
+}
+
+// WithLeeway makes Verify tolerate a clock skew of up to d when validating
+// the license times, so that a license expired less than d ago still passes
+// verification. Unlike WithGracePeriod, no error is returned for such
+// licenses. By default, no skew is tolerated.
+func WithLeeway(d time.Duration) jwt.ParseOption {
+	return jwt.WithAcceptableSkew(d)
+}
+
+// WithRequiredClaims makes Verify reject licenses missing any of the claims
+// names with ErrMalformedClaims. The claims are only looked up once the
+// signature of the license has been verified.
+func WithRequiredClaims(names ...string) jwt.ParseOption {
+	return newVerifyOption(func(o *verifyOptions) {
+		o.requiredClaims = append(o.requiredClaims, names...)
+	})
+}
+
+// WithExpiryWarning makes Verify call fn with the license info and the time
+// remaining until the license expires, if it is valid and expires within
+// threshold. The outcome of Verify is not affected by fn.
+func WithExpiryWarning(threshold time.Duration, fn func(LicenseInfo, time.Duration)) jwt.ParseOption {
+	return newVerifyOption(func(o *verifyOptions) {
+		o.expiryWarning = threshold
+		o.onExpiryWarning = fn
+	})
+}
+
+// WithNormalizeStrings makes Verify normalize the organization name and the
+// domain of the email to Unicode NFC, so that they can be compared with
+// values stored in a different normal form. The original values are kept in
+// LicenseInfo.Raw when they differ.
+func WithNormalizeStrings() jwt.ParseOption {
+	return newVerifyOption(func(o *verifyOptions) {
+		o.normalize = true
+	})
+}
+
+// WithIgnoreExpiry makes Verify accept expired licenses, for instance to
+// check that an expired license is genuine. The signature and the other
+// claims are verified as usual; for expired licenses, Verify returns the
+// license info along with an error wrapping ErrLicenseExpired. Unlike
+// Inspect, which verifies nothing, the returned license info is trusted.
+func WithIgnoreExpiry() jwt.ParseOption {
+	return newVerifyOption(func(o *verifyOptions) {
+		o.ignoreExpiry = true
+	})
+}
+
+// WithAllowedAccountIDs makes Verify reject licenses issued to accounts other
+// than ids with ErrAccountNotAllowed. Without ids, all accounts are allowed.
+func WithAllowedAccountIDs(ids ...int64) jwt.ParseOption {
+	return newVerifyOption(func(o *verifyOptions) {
+		if len(ids) == 0 {
+			return
+		}
+		if o.accountIDs == nil {
+			o.accountIDs = make(map[int64]struct{}, len(ids))
+		}
+		for _, id := range ids {
+			o.accountIDs[id] = struct{}{}
+		}
+	})
+}
+
+// WithAllowedParentAccountIDs makes Verify reject licenses other than those
+// of the sub-accounts of ids with ErrAccountNotAllowed, so that a reseller
+// accepts the licenses of any account it manages. Licenses without parent
+// account are rejected. Without ids, all parent accounts are allowed.
+// It applies in addition to WithAllowedAccountIDs.
+func WithAllowedParentAccountIDs(ids ...int64) jwt.ParseOption {
+	return newVerifyOption(func(o *verifyOptions) {
+		if len(ids) == 0 {
+			return
+		}
+		if o.parentAccountIDs == nil {
+			o.parentAccountIDs = make(map[int64]struct{}, len(ids))
+		}
+		for _, id := range ids {
+			o.parentAccountIDs[id] = struct{}{}
+		}
+	})
+}
+
+// WithPlanDefaults makes Verify fill the storage capacity and the maximum
+// number of nodes of licenses omitting them with the limits of their plan in
+// d. Claims present in the license always win. A license without capacity is
+// still rejected if its plan has no default capacity; the number of nodes of
+// plans without defaults stays zero, i.e. unlimited.
+func WithPlanDefaults(d PlanDefaults) jwt.ParseOption {
+	return newVerifyOption(func(o *verifyOptions) {
+		o.planDefaults = d
+	})
+}
+
+// WithUsage makes Verify reject licenses whose storage capacity is less than
+// usedBytes with ErrCapacityExceeded. Licenses without storage capacity are
+// unlimited and never exceeded.
+func WithUsage(usedBytes int64) jwt.ParseOption {
+	return newVerifyOption(func(o *verifyOptions) {
+		o.usedBytes = usedBytes
+		o.checkUsage = true
+	})
+}
+
+// WithValidator makes Verify run fn on the license info once all the other
+// checks have passed, including for licenses in the grace period, so that
+// call sites can enforce their own rules. A non-nil error returned by fn is
+// wrapped in the VerifyError returned by Verify. It can be given several
+// times, the validators then run in order until one fails.
+func WithValidator(fn func(LicenseInfo) error) jwt.ParseOption {
+	return newVerifyOption(func(o *verifyOptions) {
+		o.validators = append(o.validators, fn)
+	})
+}
+
+// WithRequireDeploymentID makes Verify reject licenses without deployment ID,
+// or with an empty one, with ErrMalformedClaims. By default, such licenses
+// are accepted since older licenses have no deployment ID.
+func WithRequireDeploymentID() jwt.ParseOption {
+	return newVerifyOption(func(o *verifyOptions) {
+		o.requireDeploymentID = true
+	})
+}
+
+// WithAllowedPlans makes Verify reject licenses on plans other than plans
+// with ErrPlanNotAllowed. Known plans are matched as ParsePlan does, i.e.
+// ignoring case and surrounding spaces, other plans must match exactly. Like
+// WithAllowedAccountIDs, it can be given several times and without plans,
+// all plans are allowed.
+func WithAllowedPlans(plans ...Plan) jwt.ParseOption {
+	return newVerifyOption(func(o *verifyOptions) {
+		if len(plans) == 0 {
+			return
+		}
+		if o.plans == nil {
+			o.plans = make(map[Plan]struct{}, len(plans))
+		}
+		for _, p := range plans {
+			o.plans[canonicalPlan(string(p))] = struct{}{}
+		}
+	})
+}
@@ -0,0 +1,280 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// PKCS#7 / CMS object identifiers.
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+)
+
+// pkcs7Digests are the digest algorithms of the CMS signatures accepted by
+// VerifyPKCS7.
+var pkcs7Digests = []struct {
+	oid  asn1.ObjectIdentifier
+	hash crypto.Hash
+}{
+	{asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}, crypto.SHA256},
+	{asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}, crypto.SHA384},
+	{asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}, crypto.SHA512},
+}
+
+// pkcs7ContentInfo is the outer structure of a PKCS#7 / CMS message.
+type pkcs7ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+// pkcs7SignedData is the signed data content of a PKCS#7 / CMS message. The
+// CRLs aren't decoded.
+type pkcs7SignedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	ContentInfo      pkcs7EncapContentInfo
+	Certificates     asn1.RawValue     `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue     `asn1:"optional,tag:1"`
+	SignerInfos      []pkcs7SignerInfo `asn1:"set"`
+}
+
+// pkcs7EncapContentInfo is the signed content of a PKCS#7 / CMS message,
+// without content for detached signatures.
+type pkcs7EncapContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     []byte `asn1:"explicit,optional,tag:0"`
+}
+
+// pkcs7SignerInfo is the signature of one signer of a PKCS#7 / CMS message.
+type pkcs7SignerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+// pkcs7Attribute is a signed attribute of a PKCS#7 / CMS signer.
+type pkcs7Attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue `asn1:"set"`
+}
+
+// NewLicenseVerifierFromPKCS7 returns an initialized license verifier with
+// the public key of the leaf certificate carried by the DER encoded PKCS#7 /
+// CMS signed data der, e.g. a detached signature. The leaf is the first
+// certificate that isn't a CA. Only the certificates are extracted, the CMS
+// signatures of der aren't verified. Licenses are verified as usual by
+// Verify, or by VerifyPKCS7 for licenses distributed as PKCS#7 / CMS.
+func NewLicenseVerifierFromPKCS7(der []byte, opts ...Option) (*LicenseVerifier, error) {
+	certs, err := pkcs7Certificates(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PKCS#7 signed data: %w", err)
+	}
+	pbKey, err := leafPublicKey(certs)
+	if err != nil {
+		return nil, err
+	}
+
+	var o verifierOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	key, err := newJWK(pbKey, o)
+	if err != nil {
+		return nil, err
+	}
+	keyset := jwk.NewSet()
+	keyset.Add(key)
+	return &LicenseVerifier{
+		keySet: keyset,
+		opts:   o,
+	}, nil
+}
+
+// VerifyPKCS7 verifies the license carried by the DER encoded PKCS#7 / CMS
+// signed data der and validates its claims like Verify. The signed content
+// must be the JSON claims of the license, signed with ECDSA by one of the
+// trusted keys of lv, with or without signed attributes. The certificates
+// carried by der aren't trusted: the license must be signed by a key of the
+// verifier, e.g. loaded with NewLicenseVerifierFromPKCS7 from a message
+// distributed separately. Detached signatures and BER encodings aren't
+// supported. The license token of the returned info is empty as the license
+// isn't a JWT.
+func (lv *LicenseVerifier) VerifyPKCS7(der []byte, options ...jwt.ParseOption) (LicenseInfo, error) {
+	vo, options := splitOptions(options)
+	payload, err := verifyPKCS7Signature(der, lv.keys())
+	if err != nil {
+		return LicenseInfo{}, signatureError(err)
+	}
+	token, err := jwt.Parse(payload, append(options[:len(options):len(options)], jwt.WithValidate(false))...)
+	if err != nil {
+		return LicenseInfo{}, fmt.Errorf("%w: %s", ErrMalformedClaims, err)
+	}
+	_, li, err := checkToken(context.Background(), "", token, vo, options)
+	return li, err
+}
+
+// verifyPKCS7Signature returns the content of the DER encoded PKCS#7 signed
+// data der if any of its signers signed it with one of the ECDSA keys.
+func verifyPKCS7Signature(der []byte, keys jwk.Set) ([]byte, error) {
+	sd, err := pkcs7Decode(der)
+	if err != nil {
+		return nil, err
+	}
+	if !sd.ContentInfo.ContentType.Equal(oidData) {
+		return nil, fmt.Errorf("signed content type %s isn't data", sd.ContentInfo.ContentType)
+	}
+	if len(sd.ContentInfo.Content) == 0 {
+		return nil, errors.New("no signed content, detached signatures aren't supported")
+	}
+	if len(sd.SignerInfos) == 0 {
+		return nil, errors.New("no signer")
+	}
+	var pubKeys []*ecdsa.PublicKey
+	for i := 0; i < keys.Len(); i++ {
+		key, _ := keys.Get(i)
+		var raw interface{}
+		if err = key.Raw(&raw); err != nil {
+			continue
+		}
+		if pubKey, ok := raw.(*ecdsa.PublicKey); ok {
+			pubKeys = append(pubKeys, pubKey)
+		}
+	}
+	if len(pubKeys) == 0 {
+		return nil, fmt.Errorf("%w: CMS signatures require an ECDSA key", ErrUnsupportedAlgorithm)
+	}
+
+	content := sd.ContentInfo.Content
+	for _, si := range sd.SignerInfos {
+		var digest []byte
+		if digest, err = pkcs7SignedDigest(si, content); err != nil {
+			continue
+		}
+		for _, pubKey := range pubKeys {
+			if ecdsa.VerifyASN1(pubKey, digest, si.Signature) {
+				return content, nil
+			}
+		}
+		err = errors.New("CMS signature doesn't match any trusted key")
+	}
+	return nil, err
+}
+
+// pkcs7SignedDigest returns the digest signed by the signer si of content:
+// the digest of the signed attributes if any, which must carry the data
+// content type and the digest of content, the digest of content otherwise.
+func pkcs7SignedDigest(si pkcs7SignerInfo, content []byte) ([]byte, error) {
+	var hash crypto.Hash
+	for _, d := range pkcs7Digests {
+		if si.DigestAlgorithm.Algorithm.Equal(d.oid) {
+			hash = d.hash
+		}
+	}
+	if hash == 0 {
+		return nil, fmt.Errorf("%w: digest algorithm %s", ErrUnsupportedAlgorithm, si.DigestAlgorithm.Algorithm)
+	}
+	h := hash.New()
+	h.Write(content)
+	digest := h.Sum(nil)
+	if len(si.SignedAttrs.FullBytes) == 0 {
+		return digest, nil
+	}
+
+	// The signed attributes are signed as a SET rather than with their
+	// implicit [0] tag.
+	signed := append([]byte{asn1.TagSet | 0x20}, si.SignedAttrs.FullBytes[1:]...)
+	var attrs []pkcs7Attribute
+	if _, err := asn1.UnmarshalWithParams(signed, &attrs, "set"); err != nil {
+		return nil, fmt.Errorf("invalid signed attributes: %w", err)
+	}
+	var hasType, hasDigest bool
+	for _, attr := range attrs {
+		switch {
+		case attr.Type.Equal(oidContentType):
+			var contentType asn1.ObjectIdentifier
+			if _, err := asn1.Unmarshal(attr.Values.Bytes, &contentType); err != nil || !contentType.Equal(oidData) {
+				return nil, errors.New("signed content type attribute isn't data")
+			}
+			hasType = true
+		case attr.Type.Equal(oidMessageDigest):
+			var md []byte
+			if _, err := asn1.Unmarshal(attr.Values.Bytes, &md); err != nil || !bytes.Equal(md, digest) {
+				return nil, errors.New("message digest attribute doesn't match the content")
+			}
+			hasDigest = true
+		}
+	}
+	if !hasType || !hasDigest {
+		return nil, errors.New("signed attributes must carry the content type and message digest")
+	}
+	h = hash.New()
+	h.Write(signed)
+	return h.Sum(nil), nil
+}
+
+// pkcs7Certificates returns the certificates of the DER encoded PKCS#7 signed
+// data der.
+func pkcs7Certificates(der []byte) ([]*x509.Certificate, error) {
+	sd, err := pkcs7Decode(der)
+	if err != nil {
+		return nil, err
+	}
+	if len(sd.Certificates.Bytes) == 0 {
+		return nil, errors.New("no certificate")
+	}
+	return x509.ParseCertificates(sd.Certificates.Bytes)
+}
+
+// pkcs7Decode returns the signed data of the DER encoded PKCS#7 message der.
+func pkcs7Decode(der []byte) (pkcs7SignedData, error) {
+	var ci pkcs7ContentInfo
+	rest, err := asn1.Unmarshal(der, &ci)
+	if err != nil {
+		return pkcs7SignedData{}, err
+	}
+	if len(rest) > 0 {
+		return pkcs7SignedData{}, errors.New("trailing data after content info")
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		return pkcs7SignedData{}, fmt.Errorf("content type %s isn't signed data", ci.ContentType)
+	}
+	var sd pkcs7SignedData
+	if _, err = asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return pkcs7SignedData{}, err
+	}
+	return sd, nil
+}
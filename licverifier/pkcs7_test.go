@@ -0,0 +1,227 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// pkcs7DER returns a DER encoded PKCS#7 message of contentType carrying the
+// certificates of the PEM encoded chain, without signer.
+func pkcs7DER(t *testing.T, contentType asn1.ObjectIdentifier, chain []byte) []byte {
+	t.Helper()
+	return pkcs7Message(t, contentType, chain, nil)
+}
+
+// pkcs7Message returns a DER encoded PKCS#7 message of contentType carrying
+// the certificates of the PEM encoded chain, the data content and the
+// signatures signers.
+func pkcs7Message(t *testing.T, contentType asn1.ObjectIdentifier, chain, content []byte, signers ...pkcs7SignerInfo) []byte {
+	t.Helper()
+	var certs []byte
+	for {
+		var block *pem.Block
+		if block, chain = pem.Decode(chain); block == nil {
+			break
+		}
+		certs = append(certs, block.Bytes...)
+	}
+	sd := pkcs7SignedData{
+		Version:          1,
+		DigestAlgorithms: asn1.RawValue{Tag: asn1.TagSet, IsCompound: true},
+		ContentInfo:      pkcs7EncapContentInfo{ContentType: oidData, Content: content},
+		SignerInfos:      signers,
+	}
+	if len(certs) > 0 {
+		sd.Certificates = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: certs}
+	}
+	data, err := asn1.Marshal(sd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := asn1.Marshal(pkcs7ContentInfo{
+		ContentType: contentType,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: data},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+// pkcs7Signer returns the SHA-256 signature of content by priv, over the
+// signed attributes attrs if any. The message digest attribute is set to the
+// digest of digested.
+func pkcs7Signer(t *testing.T, priv *ecdsa.PrivateKey, content, digested []byte, attrs bool) pkcs7SignerInfo {
+	t.Helper()
+	si := pkcs7SignerInfo{
+		Version:            1,
+		SID:                asn1.RawValue{Tag: asn1.TagSequence, IsCompound: true},
+		DigestAlgorithm:    pkix.AlgorithmIdentifier{Algorithm: pkcs7Digests[0].oid},
+		SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}},
+	}
+	signed := content
+	if attrs {
+		contentType, err := asn1.Marshal(oidData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256(digested)
+		md, err := asn1.Marshal(sum[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		set, err := asn1.MarshalWithParams([]pkcs7Attribute{
+			{Type: oidContentType, Values: asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: contentType}},
+			{Type: oidMessageDigest, Values: asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: md}},
+		}, "set")
+		if err != nil {
+			t.Fatal(err)
+		}
+		signed = set
+		si.SignedAttrs = asn1.RawValue{FullBytes: append([]byte{0xa0}, set[1:]...)}
+	}
+	sum := sha256.Sum256(signed)
+	sig, err := ecdsa.SignASN1(rand.Reader, priv, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	si.Signature = sig
+	return si
+}
+
+func TestNewLicenseVerifierFromPKCS7(t *testing.T) {
+	priv := newTestECKey(t)
+	lv, err := NewLicenseVerifierFromPKCS7(pkcs7DER(t, oidSignedData, certChainPEM(t, &priv.PublicKey)))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	licInfo, err := lv.Verify(signTestLicense(t, jwa.ES384, priv, nil))
+	if err != nil {
+		t.Fatalf("Expected license to pass verification but failed with %s", err)
+	}
+	if licInfo.Organization != "Example Inc." {
+		t.Fatalf("Unexpected license info %v", licInfo)
+	}
+	if _, err = lv.Verify(signTestLicense(t, jwa.ES384, newTestECKey(t), nil)); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("Expected license signed by another key to fail verification, got %v", err)
+	}
+
+	valid := pkcs7DER(t, oidSignedData, certChainPEM(t, &priv.PublicKey))
+	for i, der := range [][]byte{
+		nil,
+		[]byte("not DER"),
+		append(valid, 0),
+		pkcs7DER(t, asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}, certChainPEM(t, &priv.PublicKey)),
+		pkcs7DER(t, oidSignedData, nil),
+	} {
+		if _, err = NewLicenseVerifierFromPKCS7(der); err == nil {
+			t.Fatalf("%d: Expected invalid PKCS#7 data to be rejected", i+1)
+		}
+	}
+}
+
+func TestVerifyPKCS7(t *testing.T) {
+	priv := newTestECKey(t)
+	chain := certChainPEM(t, &priv.PublicKey)
+	lv, err := NewLicenseVerifierFromPKCS7(pkcs7DER(t, oidSignedData, chain))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	claims := func(c map[string]interface{}) []byte {
+		payload, err := base64.RawURLEncoding.DecodeString(strings.Split(signTestLicense(t, jwa.ES384, priv, c), ".")[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return payload
+	}
+	payload := claims(map[string]interface{}{deploymentID: "abc123"})
+	other := newTestECKey(t)
+
+	for i, der := range [][]byte{
+		pkcs7Message(t, oidSignedData, chain, payload, pkcs7Signer(t, priv, payload, payload, true)),
+		pkcs7Message(t, oidSignedData, nil, payload, pkcs7Signer(t, priv, payload, payload, false)),
+		pkcs7Message(t, oidSignedData, nil, payload, pkcs7Signer(t, other, payload, payload, true), pkcs7Signer(t, priv, payload, payload, true)),
+	} {
+		li, err := lv.VerifyPKCS7(der)
+		if err != nil {
+			t.Fatalf("%d: Expected license to pass verification but failed with %s", i+1, err)
+		}
+		if li.Organization != "Example Inc." || li.DeploymentID != "abc123" || li.StorageCapacity != 50 || li.LicenseToken != "" {
+			t.Fatalf("%d: Unexpected license info %v", i+1, li)
+		}
+	}
+
+	// Licenses signed only by the certificate they carry aren't trusted, nor
+	// are messages without signer or content or whose content was changed.
+	otherChain := certChainPEM(t, &other.PublicKey)
+	tampered := append(payload[:len(payload):len(payload)], ' ')
+	for i, der := range [][]byte{
+		nil,
+		[]byte("not DER"),
+		pkcs7Message(t, oidSignedData, otherChain, payload, pkcs7Signer(t, other, payload, payload, true)),
+		pkcs7Message(t, oidSignedData, chain, payload),
+		pkcs7Message(t, oidSignedData, chain, nil, pkcs7Signer(t, priv, payload, payload, true)),
+		pkcs7Message(t, oidSignedData, chain, tampered, pkcs7Signer(t, priv, payload, payload, true)),
+		pkcs7Message(t, oidSignedData, chain, tampered, pkcs7Signer(t, priv, payload, payload, false)),
+		pkcs7Message(t, oidSignedData, chain, payload, pkcs7Signer(t, priv, payload, tampered, true)),
+	} {
+		if _, err = lv.VerifyPKCS7(der); !errors.Is(err, ErrInvalidSignature) {
+			t.Fatalf("%d: Expected error %v but got %v", i+1, ErrInvalidSignature, err)
+		}
+	}
+
+	// The claims are validated like those of licenses verified by Verify.
+	expired := claims(map[string]interface{}{jwt.ExpirationKey: time.Now().Add(-time.Hour)})
+	if _, err = lv.VerifyPKCS7(pkcs7Message(t, oidSignedData, nil, expired, pkcs7Signer(t, priv, expired, expired, true))); !errors.Is(err, ErrLicenseExpired) {
+		t.Fatalf("Expected error %v but got %v", ErrLicenseExpired, err)
+	}
+	if _, err = lv.VerifyPKCS7(pkcs7Message(t, oidSignedData, nil, payload, pkcs7Signer(t, priv, payload, payload, true)), WithAllowedAccountIDs(2)); !errors.Is(err, ErrAccountNotAllowed) {
+		t.Fatalf("Expected error %v but got %v", ErrAccountNotAllowed, err)
+	}
+	notJSON := []byte("not JSON")
+	if _, err = lv.VerifyPKCS7(pkcs7Message(t, oidSignedData, nil, notJSON, pkcs7Signer(t, priv, notJSON, notJSON, true))); !errors.Is(err, ErrMalformedClaims) {
+		t.Fatalf("Expected error %v but got %v", ErrMalformedClaims, err)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaLV, err := NewLicenseVerifier(publicKeyPEM(t, &rsaKey.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	if _, err = rsaLV.VerifyPKCS7(pkcs7Message(t, oidSignedData, nil, payload, pkcs7Signer(t, priv, payload, payload, true))); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Fatalf("Expected error %v but got %v", ErrUnsupportedAlgorithm, err)
+	}
+}
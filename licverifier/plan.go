@@ -0,0 +1,97 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Plan is a Subnet license plan.
+type Plan string
+
+// Known Subnet plans.
+const (
+	PlanStandard       Plan = "STANDARD"
+	PlanEnterprise     Plan = "ENTERPRISE"
+	PlanEnterpriseLite Plan = "ENTERPRISE-LITE"
+	PlanEnterprisePlus Plan = "ENTERPRISE-PLUS"
+	PlanTrial          Plan = "TRIAL"
+)
+
+var knownPlans = []Plan{
+	PlanStandard,
+	PlanEnterprise,
+	PlanEnterpriseLite,
+	PlanEnterprisePlus,
+	PlanTrial,
+}
+
+// ParsePlan returns the known plan matching s, ignoring case and surrounding
+// spaces.
+func ParsePlan(s string) (Plan, error) {
+	p := Plan(strings.ToUpper(strings.TrimSpace(s)))
+	if !p.IsValid() {
+		return "", fmt.Errorf("unknown plan %q", s)
+	}
+	return p, nil
+}
+
+// canonicalPlan returns the known plan matching name as parsed by ParsePlan,
+// or name as is for unknown plans.
+func canonicalPlan(name string) Plan {
+	if p, err := ParsePlan(name); err == nil {
+		return p
+	}
+	return Plan(name)
+}
+
+// IsValid returns true if p is one of the known plans.
+func (p Plan) IsValid() bool {
+	for _, known := range knownPlans {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the plan name.
+func (p Plan) String() string {
+	return string(p)
+}
+
+// PlanLimits are the limits granted by a plan.
+type PlanLimits struct {
+	Capacity Capacity // storage capacity in TB, zero if the plan has no default
+	MaxNodes int64    // maximum number of nodes, zero means no limit
+}
+
+// PlanDefaults maps plans to the limits applied by Verify to the licenses
+// of the plan that omit them, see WithPlanDefaults.
+type PlanDefaults map[Plan]PlanLimits
+
+// limits returns the default limits of the plan named name, matched as is or
+// as parsed by ParsePlan.
+func (d PlanDefaults) limits(name string) (PlanLimits, bool) {
+	if limits, ok := d[Plan(name)]; ok {
+		return limits, true
+	}
+	limits, ok := d[canonicalPlan(name)]
+	return limits, ok
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+func TestParsePlan(t *testing.T) {
+	testCases := []struct {
+		s          string
+		expected   Plan
+		shouldPass bool
+	}{
+		{"STANDARD", PlanStandard, true},
+		{"enterprise", PlanEnterprise, true},
+		{" Trial ", PlanTrial, true},
+		{"ENTERPRISE-PLUS", PlanEnterprisePlus, true},
+		{"entreprise", "", false},
+		{"", "", false},
+	}
+	for i, tc := range testCases {
+		p, err := ParsePlan(tc.s)
+		if tc.shouldPass && err != nil {
+			t.Fatalf("%d: Expected %q to parse but failed with %s", i+1, tc.s, err)
+		}
+		if !tc.shouldPass && err == nil {
+			t.Fatalf("%d: Expected %q to fail parsing", i+1, tc.s)
+		}
+		if p != tc.expected {
+			t.Fatalf("%d: Expected plan %q but got %q", i+1, tc.expected, p)
+		}
+	}
+
+	if Plan("entreprise").IsValid() {
+		t.Fatal("Expected unknown plan to be invalid")
+	}
+}
+
+func TestWithPlanDefaults(t *testing.T) {
+	priv := newTestECKey(t)
+	lv, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	defaults := WithPlanDefaults(PlanDefaults{
+		PlanEnterprise: {Capacity: 100, MaxNodes: 16},
+		PlanTrial:      {MaxNodes: 4},
+	})
+
+	testCases := []struct {
+		claims      map[string]interface{}
+		expectedCap Capacity
+		expectedMax int64
+		expectedErr error
+	}{
+		// missing claims, the defaults apply
+		{map[string]interface{}{plan: "ENTERPRISE", capacity: nil}, 100, 16, nil},
+		{map[string]interface{}{plan: "enterprise", capacity: nil}, 100, 16, nil},
+		// present claims win over the defaults
+		{map[string]interface{}{plan: "ENTERPRISE", capacity: 10, maxNodes: 2}, 10, 2, nil},
+		{map[string]interface{}{plan: "ENTERPRISE", capacity: 10, maxNodes: 0}, 10, 0, nil},
+		// no defaults for the plan
+		{map[string]interface{}{plan: "STANDARD"}, 50, 0, nil},
+		{map[string]interface{}{plan: "STANDARD", capacity: nil}, 0, 0, ErrMalformedClaims},
+		// no default capacity for the plan
+		{map[string]interface{}{plan: "TRIAL"}, 50, 4, nil},
+		{map[string]interface{}{plan: "TRIAL", capacity: nil}, 0, 0, ErrMalformedClaims},
+		// invalid claims aren't replaced
+		{map[string]interface{}{plan: "ENTERPRISE", capacity: "lots"}, 0, 0, ErrMalformedClaims},
+	}
+	for i, tc := range testCases {
+		li, err := lv.Verify(signTestLicense(t, jwa.ES384, priv, tc.claims), defaults)
+		if !errors.Is(err, tc.expectedErr) || (tc.expectedErr == nil && err != nil) {
+			t.Fatalf("%d: Expected error %v but got %v", i+1, tc.expectedErr, err)
+		}
+		if li.Cap != tc.expectedCap || li.MaxNodes != tc.expectedMax {
+			t.Fatalf("%d: Expected capacity %d and %d nodes but got %d and %d", i+1, tc.expectedCap, tc.expectedMax, li.Cap, li.MaxNodes)
+		}
+	}
+
+	// Without the option, a missing capacity is rejected.
+	if _, err = lv.Verify(signTestLicense(t, jwa.ES384, priv, map[string]interface{}{plan: "ENTERPRISE", capacity: nil})); !errors.Is(err, ErrMalformedClaims) {
+		t.Fatalf("Expected %v but got %v", ErrMalformedClaims, err)
+	}
+}
+
+func TestWithAllowedPlans(t *testing.T) {
+	priv := newTestECKey(t)
+	lv, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+
+	testCases := []struct {
+		plan        string
+		options     []jwt.ParseOption
+		expectedErr error
+	}{
+		{"STANDARD", nil, nil},
+		{"STANDARD", []jwt.ParseOption{WithAllowedPlans()}, nil},
+		{"STANDARD", []jwt.ParseOption{WithAllowedPlans(PlanStandard)}, nil},
+		{"enterprise", []jwt.ParseOption{WithAllowedPlans(PlanStandard, PlanEnterprise)}, nil},
+		{"ENTERPRISE", []jwt.ParseOption{WithAllowedPlans("enterprise")}, nil},
+		{"ENTERPRISE", []jwt.ParseOption{WithAllowedPlans(PlanStandard), WithAllowedPlans(PlanEnterprise)}, nil},
+		{"CUSTOM", []jwt.ParseOption{WithAllowedPlans("CUSTOM")}, nil},
+		{"TRIAL", []jwt.ParseOption{WithAllowedPlans(PlanStandard, PlanEnterprise)}, ErrPlanNotAllowed},
+		{"CUSTOM", []jwt.ParseOption{WithAllowedPlans(PlanStandard)}, ErrPlanNotAllowed},
+		{"custom", []jwt.ParseOption{WithAllowedPlans("CUSTOM")}, ErrPlanNotAllowed},
+	}
+	for i, tc := range testCases {
+		_, err := lv.Verify(signTestLicense(t, jwa.ES384, priv, map[string]interface{}{plan: tc.plan}), tc.options...)
+		if !errors.Is(err, tc.expectedErr) || (tc.expectedErr == nil && err != nil) {
+			t.Fatalf("%d: Expected error %v but got %v", i+1, tc.expectedErr, err)
+		}
+		if tc.expectedErr != nil && verifyResult(err) != ResultPlanNotAllowed {
+			t.Fatalf("%d: Expected result %s but got %s", i+1, ResultPlanNotAllowed, verifyResult(err))
+		}
+		var verr *VerifyError
+		if tc.expectedErr != nil && (!errors.As(err, &verr) || verr.Info.Plan != tc.plan) {
+			t.Fatalf("%d: Expected a VerifyError with the license info but got %v", i+1, err)
+		}
+	}
+}
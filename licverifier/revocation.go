@@ -0,0 +1,131 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// revokedClaim is the claim of a revocation feed listing the revoked
+// deployment IDs.
+const revokedClaim = "revoked"
+
+// RevocationList is a set of deployment IDs whose licenses are revoked. It is
+// safe for concurrent use.
+type RevocationList struct {
+	mu  sync.RWMutex
+	ids map[string]struct{}
+
+	feedIssuedAt time.Time // issue time of the last feed loaded by LoadSignedFeed
+}
+
+// NewRevocationList returns a revocation list with the given deployment IDs.
+func NewRevocationList(deploymentIDs ...string) *RevocationList {
+	rl := &RevocationList{}
+	rl.Replace(deploymentIDs...)
+	return rl
+}
+
+// Replace atomically replaces the revoked deployment IDs.
+func (rl *RevocationList) Replace(deploymentIDs ...string) {
+	ids := make(map[string]struct{}, len(deploymentIDs))
+	for _, id := range deploymentIDs {
+		if id != "" {
+			ids[id] = struct{}{}
+		}
+	}
+	rl.mu.Lock()
+	rl.ids = ids
+	rl.mu.Unlock()
+}
+
+// LoadSignedFeed replaces the revoked deployment IDs with those of feed, a
+// JWT signed by one of the keys trusted by verifier and listing the revoked
+// deployment IDs in its revoked claim, e.g.
+//
+//	{"iat": 1717200000, "revoked": ["abc123", "def456"]}
+//
+// The feed must carry its issue time: a feed issued before the last one
+// loaded is rejected, so that replaying an older feed can't lift
+// revocations. The exp and nbf claims are validated if present. On error,
+// the revoked deployment IDs are left unchanged.
+func (rl *RevocationList) LoadSignedFeed(feed []byte, verifier *LicenseVerifier) error {
+	keys, baseOpts := verifier.keysWithOptions()
+	token, err := parse(strings.TrimSpace(string(feed)), keys, baseOpts, nil)
+	if err != nil {
+		return fmt.Errorf("revocation feed: %w", signatureError(err))
+	}
+	if err = jwt.Validate(token, jwt.WithClock(defaultClock)); err != nil {
+		return fmt.Errorf("revocation feed: %w", validationError(err))
+	}
+	issuedAt := token.IssuedAt()
+	if issuedAt.IsZero() {
+		return fmt.Errorf("revocation feed: %w: missing %s", ErrMalformedClaims, jwt.IssuedAtKey)
+	}
+	v, ok := token.Get(revokedClaim)
+	if !ok {
+		return fmt.Errorf("revocation feed: %w: missing %s", ErrMalformedClaims, revokedClaim)
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return fmt.Errorf("revocation feed: %w", errInvalidClaim(revokedClaim))
+	}
+	ids := make(map[string]struct{}, len(list))
+	for _, v := range list {
+		id, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("revocation feed: %w", errInvalidClaim("revoked deployment ID"))
+		}
+		if id != "" {
+			ids[id] = struct{}{}
+		}
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if issuedAt.Before(rl.feedIssuedAt) {
+		return fmt.Errorf("revocation feed issued at %s is older than the loaded one issued at %s", issuedAt, rl.feedIssuedAt)
+	}
+	rl.ids, rl.feedIssuedAt = ids, issuedAt
+	return nil
+}
+
+// IsRevoked returns true if the licenses of deploymentID are revoked. A nil
+// revocation list revokes nothing.
+func (rl *RevocationList) IsRevoked(deploymentID string) bool {
+	if rl == nil {
+		return false
+	}
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+	_, ok := rl.ids[deploymentID]
+	return ok
+}
+
+// WithRevocationList makes Verify reject licenses of deployments revoked in
+// rl with ErrLicenseRevoked.
+func WithRevocationList(rl *RevocationList) jwt.ParseOption {
+	return newVerifyOption(func(o *verifyOptions) {
+		o.revocationList = rl
+	})
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// signTestFeed returns a revocation feed carrying claims, signed with key.
+// A nil claim value leaves the claim out.
+func signTestFeed(t *testing.T, key interface{}, claims map[string]interface{}) []byte {
+	t.Helper()
+	token := jwt.New()
+	for k, v := range claims {
+		if v != nil {
+			token.Set(k, v)
+		}
+	}
+	signed, err := jwt.Sign(token, jwa.ES384, key)
+	if err != nil {
+		t.Fatalf("Failed to sign revocation feed: %s", err)
+	}
+	return signed
+}
+
+func TestWithRevocationList(t *testing.T) {
+	priv := newTestECKey(t)
+	lv, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	revoked := signTestLicense(t, jwa.ES384, priv, map[string]interface{}{deploymentID: "revoked-id"})
+	valid := signTestLicense(t, jwa.ES384, priv, map[string]interface{}{deploymentID: "valid-id"})
+	rl := NewRevocationList("revoked-id")
+
+	if _, err = lv.Verify(revoked, WithRevocationList(rl)); !errors.Is(err, ErrLicenseRevoked) {
+		t.Fatalf("Expected revoked license to fail verification, got %v", err)
+	}
+	if _, err = lv.Verify(valid, WithRevocationList(rl)); err != nil {
+		t.Fatalf("Expected license to pass verification but failed with %s", err)
+	}
+	if _, err = lv.Verify(revoked, WithRevocationList(nil)); err != nil {
+		t.Fatalf("Expected license to pass verification with a nil list but failed with %s", err)
+	}
+
+	rl.Replace("valid-id")
+	if _, err = lv.Verify(revoked, WithRevocationList(rl)); err != nil {
+		t.Fatalf("Expected license to pass verification after replacement but failed with %s", err)
+	}
+	if _, err = lv.Verify(valid, WithRevocationList(rl)); !errors.Is(err, ErrLicenseRevoked) {
+		t.Fatalf("Expected license revoked by replacement to fail verification, got %v", err)
+	}
+}
+
+func TestLoadSignedFeed(t *testing.T) {
+	priv := newTestECKey(t)
+	lv, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	now := time.Now().Truncate(time.Second)
+
+	testCases := []struct {
+		feed        []byte
+		expectedErr error
+		errContains string
+		revoked     []string // revoked deployment IDs after loading the feed
+	}{
+		{signTestFeed(t, priv, map[string]interface{}{jwt.IssuedAtKey: now.Add(-time.Hour), revokedClaim: []string{"a", "b"}}), nil, "", []string{"a", "b"}},
+		{signTestFeed(t, priv, map[string]interface{}{jwt.IssuedAtKey: now, revokedClaim: []string{"c"}}), nil, "", []string{"c"}},
+		// feeds rejected without changing the list
+		{signTestFeed(t, newTestECKey(t), map[string]interface{}{jwt.IssuedAtKey: now, revokedClaim: []string{}}), ErrInvalidSignature, "", []string{"c"}},
+		{[]byte("not a feed"), ErrInvalidSignature, "", []string{"c"}},
+		{signTestFeed(t, priv, map[string]interface{}{jwt.IssuedAtKey: now.Add(-time.Minute), revokedClaim: []string{}}), nil, "older", []string{"c"}},
+		{signTestFeed(t, priv, map[string]interface{}{revokedClaim: []string{}}), ErrMalformedClaims, "", []string{"c"}},
+		{signTestFeed(t, priv, map[string]interface{}{jwt.IssuedAtKey: now}), ErrMalformedClaims, "", []string{"c"}},
+		{signTestFeed(t, priv, map[string]interface{}{jwt.IssuedAtKey: now, revokedClaim: "c"}), ErrMalformedClaims, "", []string{"c"}},
+		{signTestFeed(t, priv, map[string]interface{}{jwt.IssuedAtKey: now, revokedClaim: []interface{}{"a", 1}}), ErrMalformedClaims, "", []string{"c"}},
+		{signTestFeed(t, priv, map[string]interface{}{jwt.IssuedAtKey: now, jwt.ExpirationKey: now.Add(-time.Minute), revokedClaim: []string{}}), ErrLicenseExpired, "", []string{"c"}},
+		// an empty feed lifts all revocations
+		{signTestFeed(t, priv, map[string]interface{}{jwt.IssuedAtKey: now, revokedClaim: []string{}}), nil, "", nil},
+	}
+	rl := NewRevocationList("x")
+	for i, tc := range testCases {
+		err := rl.LoadSignedFeed(tc.feed, lv)
+		switch {
+		case tc.expectedErr != nil:
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("%d: Expected error %v but got %v", i+1, tc.expectedErr, err)
+			}
+		case tc.errContains != "":
+			if err == nil || !strings.Contains(err.Error(), tc.errContains) {
+				t.Fatalf("%d: Expected error containing %q but got %v", i+1, tc.errContains, err)
+			}
+		case err != nil:
+			t.Fatalf("%d: Expected feed to load but failed with %s", i+1, err)
+		}
+		for _, id := range []string{"a", "b", "c", "x"} {
+			expected := false
+			for _, revoked := range tc.revoked {
+				expected = expected || id == revoked
+			}
+			if got := rl.IsRevoked(id); got != expected {
+				t.Fatalf("%d: Expected revocation of %s to be %v but got %v", i+1, id, expected, got)
+			}
+		}
+	}
+}
+
+func TestRevocationListConcurrent(t *testing.T) {
+	rl := NewRevocationList("a")
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				rl.IsRevoked("a")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				rl.Replace("a", "b")
+			}
+		}()
+	}
+	wg.Wait()
+	if !rl.IsRevoked("b") || rl.IsRevoked("c") {
+		t.Fatal("Unexpected revocation list state")
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// LicenseSigner produces license keys verifiable by a LicenseVerifier. It is
+// meant for tests and tooling.
+type LicenseSigner struct {
+	key *ecdsa.PrivateKey
+	alg jwa.SignatureAlgorithm
+}
+
+// SignOption configures how a license is signed.
+type SignOption func(*signOptions)
+
+type signOptions struct {
+	keyID string
+}
+
+// WithKeyID sets the kid header of the signed license.
+func WithKeyID(kid string) SignOption {
+	return func(o *signOptions) {
+		o.keyID = kid
+	}
+}
+
+// NewLicenseSigner returns a license signer using the given ECDSA private key
+// in SEC1 ("EC PRIVATE KEY") or PKCS#8 ("PRIVATE KEY") PEM format. Licenses
+// are signed with ES256, ES384 or ES512 depending on the curve of the key.
+func NewLicenseSigner(pemBytes []byte) (*LicenseSigner, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("key must be a PEM encoded EC private key")
+	}
+	var key *ecdsa.PrivateKey
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		var err error
+		if key, err = x509.ParseECPrivateKey(block.Bytes); err != nil {
+			return nil, fmt.Errorf("Failed to parse private key: %s", err)
+		}
+	case "PRIVATE KEY":
+		pkcs8Key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse private key: %s", err)
+		}
+		var ok bool
+		if key, ok = pkcs8Key.(*ecdsa.PrivateKey); !ok {
+			return nil, fmt.Errorf("PKCS#8 private key has unsupported type %T, expected ECDSA", pkcs8Key)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q, expected \"EC PRIVATE KEY\" or \"PRIVATE KEY\"", block.Type)
+	}
+	alg, err := curveAlgorithm(key.Curve)
+	if err != nil {
+		return nil, err
+	}
+	return &LicenseSigner{key: key, alg: alg}, nil
+}
+
+// curveAlgorithm returns the ECDSA signature algorithm matching curve.
+func curveAlgorithm(curve elliptic.Curve) (jwa.SignatureAlgorithm, error) {
+	switch curve {
+	case elliptic.P256():
+		return jwa.ES256, nil
+	case elliptic.P384():
+		return jwa.ES384, nil
+	case elliptic.P521():
+		return jwa.ES512, nil
+	}
+	return "", fmt.Errorf("unsupported curve %s", curve.Params().Name)
+}
+
+// Sign returns a license key carrying the claims of info. The license token
+// field of info is ignored, optional claims are only set when non-zero.
+func (ls *LicenseSigner) Sign(info LicenseInfo, opts ...SignOption) (string, error) {
+	var o signOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	token, err := licenseToken(info)
+	if err != nil {
+		return "", err
+	}
+	var signOpts []jwt.SignOption
+	if o.keyID != "" {
+		hdrs := jws.NewHeaders()
+		if err = hdrs.Set(jws.KeyIDKey, o.keyID); err != nil {
+			return "", err
+		}
+		signOpts = append(signOpts, jwt.WithHeaders(hdrs))
+	}
+	signed, err := jwt.Sign(token, ls.alg, ls.key, signOpts...)
+	if err != nil {
+		return "", err
+	}
+	return string(signed), nil
+}
+
+// SignDetached is like Sign but returns the license with a detached payload:
+// payload holds the claims JSON and signature the license with an empty
+// payload, i.e. header..signature, as verified by VerifyDetached.
+func (ls *LicenseSigner) SignDetached(info LicenseInfo, opts ...SignOption) (payload []byte, signature string, err error) {
+	lic, err := ls.Sign(info, opts...)
+	if err != nil {
+		return nil, "", err
+	}
+	parts := strings.Split(lic, ".")
+	if payload, err = base64.RawURLEncoding.DecodeString(parts[1]); err != nil {
+		return nil, "", err
+	}
+	return payload, parts[0] + ".." + parts[2], nil
+}
+
+// licenseToken returns a JWT carrying the claims of info.
+func licenseToken(info LicenseInfo) (jwt.Token, error) {
+	claims := map[string]interface{}{
+		jwt.SubjectKey:    info.subject(),
+		jwt.ExpirationKey: info.ExpiresAt,
+		organization:      info.Organization,
+		accountID:         info.AccountID,
+		capacity:          info.StorageCapacity,
+		plan:              info.Plan,
+	}
+	// Capacities that aren't a whole number of TB are signed in bytes, so
+	// that the exact capacity is verified.
+	if n := info.CapacityBytes(); n != Capacity(info.StorageCapacity).Bytes() {
+		claims[capacity] = strconv.FormatInt(n, 10) + "B"
+	}
+	for name, v := range map[string]string{
+		licenseID:     info.LicenseID,
+		deploymentID:  info.DeploymentID,
+		apiKey:        info.APIKey,
+		jwt.IssuerKey: info.Issuer,
+	} {
+		if v != "" {
+			claims[name] = v
+		}
+	}
+	if !info.IssuedAt.IsZero() {
+		claims[jwt.IssuedAtKey] = info.IssuedAt
+	}
+	if !info.NotBefore.IsZero() {
+		claims[jwt.NotBeforeKey] = info.NotBefore
+	}
+	if info.ParentAccountID != 0 {
+		claims[parentAccID] = info.ParentAccountID
+	}
+	if info.MaxNodes != 0 {
+		claims[maxNodes] = info.MaxNodes
+	}
+	if info.IsTrial {
+		claims[trial] = true
+	}
+	if len(info.Features) > 0 {
+		claims[features] = info.Features
+	}
+	for name, v := range info.Extra {
+		if _, ok := knownClaims[name]; !ok {
+			claims[name] = v
+		}
+	}
+
+	token := jwt.New()
+	for name, v := range claims {
+		if err := token.Set(name, v); err != nil {
+			return nil, fmt.Errorf("unable to set claim %s: %w", name, err)
+		}
+	}
+	return token, nil
+}
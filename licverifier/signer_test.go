@@ -0,0 +1,189 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ecPrivateKeyPEM returns the SEC1 PEM encoding of priv.
+func ecPrivateKeyPEM(t *testing.T, priv *ecdsa.PrivateKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalECPrivateKey(priv)
+	if err != nil {
+		t.Fatalf("Failed to marshal private key: %s", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+// pkcs8PrivateKeyPEM returns the PKCS#8 PEM encoding of priv.
+func pkcs8PrivateKeyPEM(t *testing.T, priv interface{}) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatalf("Failed to marshal private key: %s", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func TestLicenseSigner(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	info := LicenseInfo{
+		LicenseID:       "lic-1",
+		Email:           "jane@example.com",
+		Subject:         "jane@example.com",
+		Organization:    "Acme",
+		AccountID:       42,
+		ParentAccountID: 7,
+		DeploymentID:    "abc123",
+		StorageCapacity: 500,
+		Cap:             500,
+		Plan:            "ENTERPRISE",
+		IssuedAt:        now.Add(-time.Hour),
+		ExpiresAt:       now.Add(time.Hour),
+		NotBefore:       now.Add(-time.Minute),
+		APIKey:          "api-key",
+		IsTrial:         true,
+		Issuer:          "subnet@min.io",
+		MaxNodes:        16,
+		Features:        map[string]bool{"replication": true},
+		Extra:           map[string]interface{}{"region": "eu-west-1"},
+	}
+
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		priv, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ls, err := NewLicenseSigner(ecPrivateKeyPEM(t, priv))
+		if err != nil {
+			t.Fatalf("%s: Failed to create license signer: %s", curve.Params().Name, err)
+		}
+		lic, err := ls.Sign(info)
+		if err != nil {
+			t.Fatalf("%s: Failed to sign license: %s", curve.Params().Name, err)
+		}
+
+		lv, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey), WithAlgorithm(ls.alg))
+		if err != nil {
+			t.Fatalf("%s: Failed to create license verifier: %s", curve.Params().Name, err)
+		}
+		licInfo, err := lv.Verify(lic)
+		if err != nil {
+			t.Fatalf("%s: Expected signed license to pass verification but failed with %s", curve.Params().Name, err)
+		}
+		expected := info
+		expected.LicenseToken = lic
+		if !reflect.DeepEqual(expected, licInfo) {
+			t.Fatalf("%s: Expected license info %#v but got %#v", curve.Params().Name, expected, licInfo)
+		}
+	}
+
+	// The exact capacity of licenses that aren't a whole number of TB is kept.
+	priv := newTestECKey(t)
+	ls, err := NewLicenseSigner(ecPrivateKeyPEM(t, priv))
+	if err != nil {
+		t.Fatalf("Failed to create license signer: %s", err)
+	}
+	lv, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey), WithAlgorithm(ls.alg))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	exact := info
+	exact.StorageCapacity, exact.StorageBytes, exact.Cap = 1, 512<<30, 1
+	lic, err := ls.Sign(exact)
+	if err != nil {
+		t.Fatalf("Failed to sign license: %s", err)
+	}
+	if licInfo, err := lv.Verify(lic); err != nil || licInfo.StorageCapacity != 1 || licInfo.CapacityBytes() != 512<<30 {
+		t.Fatalf("Expected capacity of 1 TB and %d bytes, got %v and %v", 512<<30, licInfo, err)
+	}
+
+	if _, err := NewLicenseSigner([]byte("not a key")); err == nil {
+		t.Fatal("Expected invalid key to be rejected")
+	}
+}
+
+// TestNewLicenseSignerEncodings tests creating signers from SEC1 and PKCS#8
+// private keys.
+func TestNewLicenseSignerEncodings(t *testing.T) {
+	priv := newTestECKey(t)
+	lv, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	info := LicenseInfo{
+		Email:           "jane@example.com",
+		Organization:    "Acme",
+		AccountID:       42,
+		StorageCapacity: 10,
+		Plan:            "STANDARD",
+		ExpiresAt:       time.Now().Add(time.Hour),
+	}
+
+	for i, pemBytes := range [][]byte{ecPrivateKeyPEM(t, priv), pkcs8PrivateKeyPEM(t, priv)} {
+		ls, err := NewLicenseSigner(pemBytes)
+		if err != nil {
+			t.Fatalf("%d: Failed to create license signer: %s", i+1, err)
+		}
+		lic, err := ls.Sign(info)
+		if err != nil {
+			t.Fatalf("%d: Failed to sign license: %s", i+1, err)
+		}
+		if _, err = lv.Verify(lic); err != nil {
+			t.Fatalf("%d: Expected signed license to pass verification but failed with %s", i+1, err)
+		}
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sec1 := ecPrivateKeyPEM(t, priv)
+	block, _ := pem.Decode(sec1)
+	testCases := []struct {
+		pemBytes    []byte
+		expectedErr string
+	}{
+		{pkcs8PrivateKeyPEM(t, rsaKey), "unsupported type *rsa.PrivateKey, expected ECDSA"},
+		{pkcs8PrivateKeyPEM(t, edKey), "unsupported type ed25519.PrivateKey, expected ECDSA"},
+		{pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: block.Bytes}), "Failed to parse private key"},
+		{pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte("garbage")}), "Failed to parse private key"},
+		{pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: block.Bytes}), `unsupported PEM block type "RSA PRIVATE KEY"`},
+	}
+	for i, tc := range testCases {
+		_, err := NewLicenseSigner(tc.pemBytes)
+		if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+			t.Fatalf("%d: Expected error containing %q but got %v", i+1, tc.expectedErr, err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// VerifyStream verifies the licenses read from r, one per line, as they are
+// read, so that large files are never loaded in memory at once. Each call of
+// the returned function reads the next license and returns the outcome of
+// its verification, with the line number added to the errors. Blank lines
+// are skipped. Once r has been read to the end, the function returns io.EOF;
+// if reading r fails it returns an error wrapping ErrStreamRead, and once ctx
+// is done it returns ctx.Err(). After any of these, it keeps returning the
+// same error. Lines longer than 64 KiB are read errors.
+//
+//	next := lv.VerifyStream(ctx, r)
+//	for {
+//		li, err := next()
+//		if err == io.EOF || errors.Is(err, ErrStreamRead) || ctx.Err() != nil {
+//			break
+//		}
+//		...
+//	}
+func (lv *LicenseVerifier) VerifyStream(ctx context.Context, r io.Reader, options ...jwt.ParseOption) func() (LicenseInfo, error) {
+	scanner := bufio.NewScanner(r)
+	line := 0
+	var done error
+	return func() (LicenseInfo, error) {
+		for done == nil {
+			if err := ctx.Err(); err != nil {
+				done = err
+				break
+			}
+			if !scanner.Scan() {
+				done = io.EOF
+				if err := scanner.Err(); err != nil {
+					done = fmt.Errorf("%w: line %d: %s", ErrStreamRead, line+1, err)
+				}
+				break
+			}
+			line++
+			lic := strings.TrimSpace(scanner.Text())
+			if lic == "" {
+				continue
+			}
+			li, err := lv.VerifyContext(ctx, lic, options...)
+			if err != nil {
+				err = fmt.Errorf("line %d: %w", line, err)
+			}
+			return li, err
+		}
+		return LicenseInfo{}, done
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package licverifier
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/lestrrat-go/jwx/jwa"
+)
+
+func TestVerifyStream(t *testing.T) {
+	priv := newTestECKey(t)
+	lv, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	lic1 := signTestLicense(t, jwa.ES384, priv, map[string]interface{}{accountID: 1})
+	lic2 := signTestLicense(t, jwa.ES384, priv, map[string]interface{}{accountID: 2})
+	untrusted := signTestLicense(t, jwa.ES384, newTestECKey(t), nil)
+
+	next := lv.VerifyStream(context.Background(), strings.NewReader("\n"+lic1+"\r\n  \n"+untrusted+"\n"+lic2))
+	testCases := []struct {
+		accountID   int64
+		expectedErr error
+		errContains string
+	}{
+		{1, nil, ""},
+		{0, ErrInvalidSignature, "line 4"},
+		{2, nil, ""},
+		{0, io.EOF, ""},
+		{0, io.EOF, ""},
+	}
+	for i, tc := range testCases {
+		li, err := next()
+		if tc.expectedErr == nil && err != nil {
+			t.Fatalf("%d: Expected license to pass verification but failed with %s", i+1, err)
+		}
+		if !errors.Is(err, tc.expectedErr) || !strings.Contains(errString(err), tc.errContains) {
+			t.Fatalf("%d: Expected error %v containing %q but got %v", i+1, tc.expectedErr, tc.errContains, err)
+		}
+		if li.AccountID != tc.accountID {
+			t.Fatalf("%d: Expected account %d but got %d", i+1, tc.accountID, li.AccountID)
+		}
+	}
+}
+
+// errString returns the message of err, empty for a nil error.
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// TestVerifyStreamIncremental tests that licenses are verified as they are
+// read rather than once the whole stream is.
+func TestVerifyStreamIncremental(t *testing.T) {
+	priv := newTestECKey(t)
+	lv, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	lic := signTestLicense(t, jwa.ES384, priv, nil)
+
+	pr, pw := io.Pipe()
+	next := lv.VerifyStream(context.Background(), pr)
+	go pw.Write([]byte(lic + "\n"))
+	if _, err = next(); err != nil {
+		t.Fatalf("Expected license to pass verification but failed with %s", err)
+	}
+	go pw.Close()
+	if _, err = next(); err != io.EOF {
+		t.Fatalf("Expected end of stream but got %v", err)
+	}
+}
+
+func TestVerifyStreamErrors(t *testing.T) {
+	priv := newTestECKey(t)
+	lv, err := NewLicenseVerifier(publicKeyPEM(t, &priv.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to create license verifier: %s", err)
+	}
+	lic := signTestLicense(t, jwa.ES384, priv, nil)
+
+	// Read errors are reported once the licenses read before are verified.
+	readErr := errors.New("disk failure")
+	next := lv.VerifyStream(context.Background(), io.MultiReader(strings.NewReader(lic+"\n"), iotest.ErrReader(readErr)))
+	if _, err = next(); err != nil {
+		t.Fatalf("Expected license to pass verification but failed with %s", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err = next(); !errors.Is(err, ErrStreamRead) || !strings.Contains(err.Error(), "disk failure") {
+			t.Fatalf("%d: Expected read error but got %v", i+1, err)
+		}
+	}
+	next = lv.VerifyStream(context.Background(), strings.NewReader(strings.Repeat("a", 128<<10)))
+	if _, err = next(); !errors.Is(err, ErrStreamRead) {
+		t.Fatalf("Expected too long line to be a read error but got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	next = lv.VerifyStream(ctx, strings.NewReader(lic+"\n"+lic+"\n"))
+	if _, err = next(); err != nil {
+		t.Fatalf("Expected license to pass verification but failed with %s", err)
+	}
+	cancel()
+	for i := 0; i < 2; i++ {
+		if _, err = next(); !errors.Is(err, context.Canceled) {
+			t.Fatalf("%d: Expected context canceled but got %v", i+1, err)
+		}
+	}
+}
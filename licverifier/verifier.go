@@ -19,61 +19,129 @@
 package licverifier
 
 import (
+	"bytes"
 	"context"
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/lestrrat-go/jwx/jwa"
 	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
 	"github.com/lestrrat-go/jwx/jwt"
+	"golang.org/x/text/unicode/norm"
 )
 
-// LicenseVerifier needs an ECDSA public key in PEM format for initialization.
+// LicenseVerifier needs an ECDSA, RSA or Ed25519 public key in PEM format for initialization.
 type LicenseVerifier struct {
+	mu     sync.RWMutex
 	keySet jwk.Set
+
+	// parse options of each key of baseSet, rebuilt when keySet changes
+	baseSet  jwk.Set
+	baseOpts [][]jwt.ParseOption
+
+	opts verifierOptions // construction options, also used to replace keys
+
+	// set for verifiers created from a remote key set
+	jwksURL string
 }
 
 // LicenseInfo holds customer metadata present in the license key.
 type LicenseInfo struct {
-	LicenseToken    string    // License token
-	LicenseID       string    // Unique id of the license
-	Email           string    // Email of the license key requestor
-	Organization    string    // Subnet organization name
-	AccountID       int64     // Subnet account id
-	DeploymentID    string    // Cluster deployment ID
-	StorageCapacity int64     // Storage capacity used in TB
-	Plan            string    // Subnet plan
-	IssuedAt        time.Time // Time of license issue
-	ExpiresAt       time.Time // Time of license expiry
-	APIKey          string    // Subnet account API Key
-	IsTrial         bool      // Is this a TRIAL license?
+	LicenseToken    string                 // License token
+	LicenseID       string                 // Unique id of the license
+	Email           string                 // Email of the license key requestor, empty if the subject isn't an email
+	Subject         string                 // Subject of the license, the email of the requestor or a customer UUID
+	Organization    string                 // Subnet organization name
+	AccountID       int64                  // Subnet account id
+	ParentAccountID int64                  // Subnet account id of the reseller managing the account, 0 if none
+	DeploymentID    string                 // Cluster deployment ID
+	StorageCapacity int64                  // Storage capacity used in TB, rounded for capacities with a unit
+	StorageBytes    int64                  // Exact storage capacity in bytes if not a whole number of TB, 0 otherwise
+	Cap             Capacity               // Storage capacity, same as StorageCapacity
+	Plan            string                 // Subnet plan
+	IssuedAt        time.Time              // Time of license issue
+	ExpiresAt       time.Time              // Time of license expiry
+	NotBefore       time.Time              // Time the license becomes valid, zero if valid from issue
+	APIKey          string                 // Subnet account API Key
+	IsTrial         bool                   // Is this a TRIAL license?
+	Issuer          string                 // Issuer of the license
+	MaxNodes        int64                  // Maximum number of server nodes, 0 means unlimited
+	Features        map[string]bool        // Features enabled or disabled by the license
+	Extra           map[string]interface{} // Claims not mapped to any other field, nil if none
+	Raw             map[string]string      // Original values of the claims changed by WithNormalizeStrings, nil if none
+
+	clock jwt.Clock // clock used by Verify, if set
 }
 
 // license key JSON field names
 const (
 	licenseID    = "lid"
 	accountID    = "aid"
+	parentAccID  = "paid"
 	deploymentID = "did"
 	organization = "org"
 	capacity     = "cap"
-	issuedAt     = "iat"
 	plan         = "plan"
 	apiKey       = "apiKey"
 	trial        = "trial"
+	maxNodes     = "nodes"
+	features     = "features"
 )
 
+// knownClaims are the claims mapped to LicenseInfo fields, all other claims
+// are kept in LicenseInfo.Extra.
+var knownClaims = map[string]struct{}{
+	licenseID:         {},
+	accountID:         {},
+	parentAccID:       {},
+	deploymentID:      {},
+	organization:      {},
+	capacity:          {},
+	plan:              {},
+	apiKey:            {},
+	trial:             {},
+	maxNodes:          {},
+	features:          {},
+	jwt.SubjectKey:    {},
+	jwt.IssuedAtKey:   {},
+	jwt.NotBeforeKey:  {},
+	jwt.ExpirationKey: {},
+	jwt.IssuerKey:     {},
+	jwt.JwtIDKey:      {},
+}
+
 // parse PEM encoded PKCS1 or PKCS8 public key
-func parseECPublicKeyFromPEM(key []byte) (*ecdsa.PublicKey, error) {
+func parsePublicKeyFromPEM(key []byte) (interface{}, error) {
 	var err error
 
 	// Parse PEM block
 	var block *pem.Block
 	if block, _ = pem.Decode(key); block == nil {
-		return nil, errors.New("key must be a PEM encoded PKCS1 or PKCS8 key")
+		return nil, errors.New("key must be a PEM encoded PKCS1 or PKCS8 key: no PEM block found")
+	}
+
+	switch block.Type {
+	case "CERTIFICATE":
+		return publicKeyFromCertChain(key)
+	case "PRIVATE KEY", "EC PRIVATE KEY", "RSA PRIVATE KEY", "ENCRYPTED PRIVATE KEY", "OPENSSH PRIVATE KEY":
+		// Don't echo anything from the block, it holds a secret.
+		return nil, fmt.Errorf("found PEM block %q, expected the PUBLIC KEY derived from it, e.g. with openssl pkey -pubout", block.Type)
 	}
 
 	// Parse the key
@@ -81,54 +149,352 @@ func parseECPublicKeyFromPEM(key []byte) (*ecdsa.PublicKey, error) {
 	if parsedKey, err = x509.ParsePKIXPublicKey(block.Bytes); err != nil {
 		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
 			parsedKey = cert.PublicKey
+		} else if block.Type != "PUBLIC KEY" {
+			return nil, fmt.Errorf("found PEM block %q, expected PUBLIC KEY or CERTIFICATE", block.Type)
 		} else {
 			return nil, err
 		}
 	}
 
-	var pkey *ecdsa.PublicKey
-	var ok bool
-	if pkey, ok = parsedKey.(*ecdsa.PublicKey); !ok {
-		return nil, errors.New("key is not a valid RSA public key")
+	switch parsedKey.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		return parsedKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T, expected ECDSA, RSA or Ed25519", parsedKey)
 	}
+}
 
-	return pkey, nil
+// publicKeyFromCertChain returns the public key of the leaf certificate of
+// the PEM encoded certificate chain, see leafPublicKey. Blocks other than
+// certificates are ignored.
+func publicKeyFromCertChain(chain []byte) (interface{}, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		if block, chain = pem.Decode(chain); block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate %d in chain: %w", len(certs)+1, err)
+		}
+		certs = append(certs, cert)
+	}
+	return leafPublicKey(certs)
 }
 
-// NewLicenseVerifier returns an initialized license verifier with the given
-// ECDSA public key in PEM format.
-func NewLicenseVerifier(pemBytes []byte) (*LicenseVerifier, error) {
-	pbKey, err := parseECPublicKeyFromPEM(pemBytes)
+// leafPublicKey returns the public key of the leaf certificate of the chain
+// certs, which must not be empty. The leaf is the first certificate that
+// isn't a CA, or the first certificate if all of them are CAs.
+func leafPublicKey(certs []*x509.Certificate) (interface{}, error) {
+	leaf := certs[0]
+	for _, cert := range certs {
+		if !cert.IsCA {
+			leaf = cert
+			break
+		}
+	}
+	switch leaf.PublicKey.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		return leaf.PublicKey, nil
+	default:
+		return nil, fmt.Errorf("leaf certificate %q of the chain has unsupported public key type %T, expected ECDSA, RSA or Ed25519", leaf.Subject, leaf.PublicKey)
+	}
+}
+
+// Option configures a LicenseVerifier at construction time.
+type Option func(*verifierOptions)
+
+type verifierOptions struct {
+	alg                 jwa.SignatureAlgorithm
+	httpClient          *http.Client
+	httpHeader          http.Header
+	insecureHTTP        bool
+	extraKeys           []extraKey
+	maxKeySize          int64
+	latency             *LatencyRecorder
+	keyFilePollInterval time.Duration
+}
+
+// extraKey is a public key registered by WithAdditionalKey.
+type extraKey struct {
+	pemBytes []byte
+	alg      jwa.SignatureAlgorithm
+}
+
+// WithAlgorithm sets the algorithm used to verify license signatures. By
+// default, ES384 is used for ECDSA keys, RS256 for RSA keys and EdDSA for
+// Ed25519 keys. The algorithm must belong to the same family as the public
+// key.
+func WithAlgorithm(alg jwa.SignatureAlgorithm) Option {
+	return func(o *verifierOptions) {
+		o.alg = alg
+	}
+}
+
+// WithAdditionalKey registers another public key in PEM format trusted by
+// NewLicenseVerifier, used to verify licenses signed with alg. It can be
+// given several times, so that licenses signed with different algorithms
+// are accepted by the same verifier: each license is verified only with
+// the keys registered for the algorithm in its header.
+func WithAdditionalKey(pemBytes []byte, alg jwa.SignatureAlgorithm) Option {
+	return func(o *verifierOptions) {
+		o.extraKeys = append(o.extraKeys, extraKey{pemBytes: pemBytes, alg: alg})
+	}
+}
+
+// DefaultMaxKeySize is the maximum size of the public key read by
+// NewLicenseVerifierFromReader, unless overridden by WithMaxKeySize.
+const DefaultMaxKeySize = 64 << 10
+
+// WithMaxKeySize sets the maximum number of bytes read from the reader given
+// to NewLicenseVerifierFromReader. It defaults to DefaultMaxKeySize.
+func WithMaxKeySize(n int64) Option {
+	return func(o *verifierOptions) {
+		o.maxKeySize = n
+	}
+}
+
+// signatureAlgorithm returns the default algorithm used to verify licenses
+// signed with the private counterpart of pbKey.
+func signatureAlgorithm(pbKey interface{}) jwa.SignatureAlgorithm {
+	switch pbKey.(type) {
+	case *rsa.PublicKey:
+		return jwa.RS256
+	case ed25519.PublicKey:
+		return jwa.EdDSA
+	}
+	return jwa.ES384
+}
+
+// checkAlgorithm returns an error if alg can't be used to verify signatures
+// with pbKey.
+func checkAlgorithm(pbKey interface{}, alg jwa.SignatureAlgorithm) error {
+	var algs []jwa.SignatureAlgorithm
+	switch pbKey.(type) {
+	case *ecdsa.PublicKey:
+		algs = []jwa.SignatureAlgorithm{jwa.ES256, jwa.ES384, jwa.ES512}
+	case *rsa.PublicKey:
+		algs = []jwa.SignatureAlgorithm{jwa.RS256, jwa.RS384, jwa.RS512, jwa.PS256, jwa.PS384, jwa.PS512}
+	case ed25519.PublicKey:
+		algs = []jwa.SignatureAlgorithm{jwa.EdDSA}
+	}
+	for _, a := range algs {
+		if a == alg {
+			return nil
+		}
+	}
+	return fmt.Errorf("algorithm %s can't be used with a %T key", alg, pbKey)
+}
+
+// newKey parses the public key in pemBytes and returns it as a JWK carrying
+// the algorithm used to verify signatures with it.
+func newKey(pemBytes []byte, o verifierOptions) (jwk.Key, error) {
+	pbKey, err := parsePublicKeyFromPEM(pemBytes)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to parse public key: %s", err)
 	}
+	return newJWK(pbKey, o)
+}
+
+// newJWK returns pbKey as a JWK carrying the algorithm used to verify
+// signatures with it.
+func newJWK(pbKey interface{}, o verifierOptions) (jwk.Key, error) {
+	alg := o.alg
+	if alg == "" {
+		alg = signatureAlgorithm(pbKey)
+	}
+	if err := checkAlgorithm(pbKey, alg); err != nil {
+		return nil, err
+	}
 	key, err := jwk.New(pbKey)
 	if err != nil {
 		return nil, err
 	}
-	key.Set(jwk.AlgorithmKey, jwa.ES384)
+	key.Set(jwk.AlgorithmKey, alg)
+	return key, nil
+}
+
+// NewLicenseVerifier returns an initialized license verifier with the given
+// ECDSA, RSA or Ed25519 public key in PEM format. Unless overridden by
+// WithAlgorithm, licenses are expected to be signed with ES384 for ECDSA keys,
+// RS256 for RSA keys and EdDSA for Ed25519 keys. More keys, possibly using
+// other algorithms, can be trusted with WithAdditionalKey.
+func NewLicenseVerifier(pemBytes []byte, opts ...Option) (*LicenseVerifier, error) {
+	var o verifierOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	key, err := newKey(pemBytes, o)
+	if err != nil {
+		return nil, err
+	}
 	keyset := jwk.NewSet()
 	keyset.Add(key)
+	if err = addExtraKeys(keyset, o); err != nil {
+		return nil, err
+	}
 	return &LicenseVerifier{
 		keySet: keyset,
+		opts:   o,
 	}, nil
 }
 
-// toLicenseInfo extracts LicenseInfo from claims. It returns an error if any of
-// the claim values are invalid.
-func toLicenseInfo(license string, token jwt.Token) (LicenseInfo, error) {
-	claims, err := token.AsMap(context.Background())
+// addExtraKeys adds the keys registered by WithAdditionalKey in o to keyset,
+// each with its own algorithm.
+func addExtraKeys(keyset jwk.Set, o verifierOptions) error {
+	for i, k := range o.extraKeys {
+		key, err := newKey(k.pemBytes, verifierOptions{alg: k.alg})
+		if err != nil {
+			return fmt.Errorf("additional key #%d: %w", i+1, err)
+		}
+		keyset.Add(key)
+	}
+	return nil
+}
+
+// NewLicenseVerifierFromFile returns an initialized license verifier with the
+// public key in PEM format read from the file at path.
+func NewLicenseVerifierFromFile(path string, opts ...Option) (*LicenseVerifier, error) {
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read public key from %s: %w", path, err)
+	}
+	if len(bytes.TrimSpace(pemBytes)) == 0 {
+		return nil, fmt.Errorf("public key file %s is empty", path)
+	}
+	return NewLicenseVerifier(pemBytes, opts...)
+}
+
+// NewLicenseVerifierFromReader returns an initialized license verifier with
+// the public key in PEM format read from r until EOF. At most
+// DefaultMaxKeySize bytes are read, or the size set with WithMaxKeySize: a
+// larger key is rejected without reading the rest of r.
+func NewLicenseVerifierFromReader(r io.Reader, opts ...Option) (*LicenseVerifier, error) {
+	var o verifierOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	maxSize := o.maxKeySize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxKeySize
+	}
+	pemBytes, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read public key: %w", err)
+	}
+	if int64(len(pemBytes)) > maxSize {
+		return nil, fmt.Errorf("public key exceeds the maximum size of %d bytes", maxSize)
+	}
+	if len(bytes.TrimSpace(pemBytes)) == 0 {
+		return nil, errors.New("public key is empty")
+	}
+	return NewLicenseVerifier(pemBytes, opts...)
+}
+
+// NewLicenseVerifierFromBase64 returns an initialized license verifier with
+// the PKIX public key in DER format encoded with standard base64, i.e. the
+// content of a PEM public key without armor on a single line.
+func NewLicenseVerifierFromBase64(b64 string, opts ...Option) (*LicenseVerifier, error) {
+	der, err := base64.StdEncoding.DecodeString(strings.TrimSpace(b64))
 	if err != nil {
-		return LicenseInfo{}, err
+		return nil, fmt.Errorf("invalid base64 public key: %w", err)
+	}
+	if _, err = x509.ParsePKIXPublicKey(der); err != nil {
+		return nil, fmt.Errorf("base64 public key isn't a PKIX public key: %w", err)
+	}
+	lv, err := NewLicenseVerifier(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("base64 public key isn't supported: %w", err)
+	}
+	return lv, nil
+}
+
+// NewLicenseVerifierWithKeys returns an initialized license verifier trusting
+// all the given ECDSA, RSA or Ed25519 public keys in PEM format. A license
+// verifies if it is signed by any of them, which allows keys to be rotated
+// without invalidating existing licenses.
+func NewLicenseVerifierWithKeys(pemBytes ...[]byte) (*LicenseVerifier, error) {
+	keyset, err := newKeySet(pemBytes, verifierOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return &LicenseVerifier{
+		keySet: keyset,
+	}, nil
+}
+
+// newKeySet returns a key set holding the public keys in pemBytes.
+func newKeySet(pemBytes [][]byte, o verifierOptions) (jwk.Set, error) {
+	if len(pemBytes) == 0 {
+		return nil, errors.New("at least one public key is required")
+	}
+	keyset := jwk.NewSet()
+	for i, b := range pemBytes {
+		key, err := newKey(b, o)
+		if err != nil {
+			return nil, fmt.Errorf("public key #%d: %w", i+1, err)
+		}
+		keyset.Add(key)
+	}
+	return keyset, nil
+}
+
+// SetKeys replaces the trusted keys of the verifier with the public keys in
+// PEM format pemBytes, like the key given to NewLicenseVerifier: the
+// algorithm set with WithAlgorithm the verifier was created with, or else the
+// default algorithm of the key type, applies to all of them. The keys
+// registered with WithAdditionalKey aren't replaced and keep their own
+// algorithms. It is safe to call while licenses are being verified: each
+// verification uses either the previous or the new keys, never a mix of
+// them. On error, the previous keys are kept. For verifiers created from a
+// remote key set, the keys are replaced again by the next Refresh.
+func (lv *LicenseVerifier) SetKeys(pemBytes ...[]byte) error {
+	keyset, err := newKeySet(pemBytes, lv.opts)
+	if err != nil {
+		return err
+	}
+	if err = addExtraKeys(keyset, lv.opts); err != nil {
+		return err
+	}
+	lv.mu.Lock()
+	lv.keySet = keyset
+	lv.mu.Unlock()
+	return nil
+}
+
+// toClaims extracts Claims from token. It returns an error if any of the claim
+// values are invalid.
+func toClaims(token jwt.Token, vo verifyOptions) (Claims, error) {
+	// The claims are read from the token rather than from token.AsMap to
+	// save allocating a map on every verification.
+	claims := token.PrivateClaims()
+	for _, name := range vo.requiredClaims {
+		if _, ok := token.Get(name); !ok {
+			return Claims{}, fmt.Errorf("%w: missing %s", ErrMalformedClaims, name)
+		}
 	}
 	accID, ok := claims[accountID].(float64)
 	if !ok || ok && accID < 0 {
-		return LicenseInfo{}, errors.New("Invalid accountId in claims")
+		return Claims{}, errInvalidClaim("accountId")
+	}
+	// parent account id is only present in the licenses of sub-accounts.
+	var parentID float64
+	if v, ok := claims[parentAccID]; ok {
+		if parentID, ok = v.(float64); !ok || parentID <= 0 || parentID != math.Trunc(parentID) {
+			return Claims{}, errInvalidClaim("parent account id")
+		}
 	}
 
 	// deployment id may not be present in older licenses.
-	// so don't fail if it's not found.
+	// so don't fail if it's not found, unless required.
 	depUUID, _ := claims[deploymentID].(string)
+	if vo.requireDeploymentID && depUUID == "" {
+		return Claims{}, fmt.Errorf("%w: missing %s", ErrMalformedClaims, deploymentID)
+	}
 
 	// license id may not be present in older licenses.
 	// so don't fail if it's not found.
@@ -136,51 +502,622 @@ func toLicenseInfo(license string, token jwt.Token) (LicenseInfo, error) {
 
 	orgName, ok := claims[organization].(string)
 	if !ok {
-		return LicenseInfo{}, errors.New("Invalid organization in claims")
-	}
-	storageCap, ok := claims[capacity].(float64)
-	if !ok {
-		return LicenseInfo{}, errors.New("Invalid storage capacity in claims")
+		return Claims{}, errInvalidClaim("organization")
 	}
 	plan, ok := claims[plan].(string)
 	if !ok {
-		return LicenseInfo{}, errors.New("Invalid plan in claims")
+		return Claims{}, errInvalidClaim("plan")
 	}
-	iAt, ok := claims[issuedAt].(time.Time)
-	if !ok {
-		return LicenseInfo{}, errors.New("Invalid issuedAt in claims")
+	if vo.strictPlan {
+		if _, err := ParsePlan(plan); err != nil {
+			return Claims{}, fmt.Errorf("%w: %s", ErrMalformedClaims, err)
+		}
 	}
+	limits, hasLimits := vo.planDefaults.limits(plan)
 
+	// capacity may be omitted if the plan has a default capacity. Newer
+	// licenses may carry it as a string with a unit, e.g. 512GiB, rather
+	// than a number of TB.
+	storageCap, ok := claims[capacity].(float64)
+	storageBytes := int64(-1) // exact bytes, only known for capacities with a unit
+	if s, isString := claims[capacity].(string); isString {
+		c, n, err := parseCapacity(s)
+		if err != nil {
+			return Claims{}, fmt.Errorf("%w: %s", ErrMalformedClaims, err)
+		}
+		storageCap, storageBytes, ok = float64(c), n, true
+	}
+	if _, present := claims[capacity]; !present && hasLimits && limits.Capacity != 0 {
+		storageCap, ok = float64(limits.Capacity), true
+	}
+	if !ok {
+		return Claims{}, errInvalidClaim("storage capacity")
+	}
+	if storageBytes < 0 {
+		storageBytes = Capacity(storageCap).Bytes()
+	}
 	// apiKey is optional as it's not present in older licenses
 	apiKey, _ := claims[apiKey].(string)
 
+	// nodes is optional as it's not present in older licenses,
+	// zero means there is no limit on the number of nodes.
+	// The plan default, if any, applies when the claim is missing.
+	nodes := float64(limits.MaxNodes)
+	if v, ok := claims[maxNodes]; ok {
+		if nodes, ok = v.(float64); !ok || nodes < 0 || nodes != math.Trunc(nodes) {
+			return Claims{}, errInvalidClaim("max nodes")
+		}
+	}
+
+	// features are optional as they're not present in older licenses
+	feats := vo.features
+	if feats == nil {
+		feats = map[string]bool{}
+	}
+	clear(feats)
+	if v, ok := claims[features]; ok {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return Claims{}, errInvalidClaim("features")
+		}
+		for name, enabled := range m {
+			if feats[name], ok = enabled.(bool); !ok {
+				return Claims{}, errInvalidClaim("feature " + name)
+			}
+		}
+	}
+
+	var extra map[string]interface{}
+	for name, v := range claims {
+		if _, ok := knownClaims[name]; ok {
+			continue
+		}
+		if extra == nil {
+			extra = reuseExtra(vo.extra)
+		}
+		extra[name] = v
+	}
+
 	// isTrial is optional as it's not present in older licenses
 	// default value = false
 	isTrial, _ := claims[trial].(bool)
 
-	return LicenseInfo{
-		LicenseToken:    license,
+	email := token.Subject()
+	var raw map[string]string
+	if vo.normalize {
+		raw = make(map[string]string)
+		if norm := norm.NFC.String(orgName); norm != orgName {
+			raw[organization], orgName = orgName, norm
+		}
+		if norm := normalizeEmail(email); isEmail(email) && norm != email {
+			raw[jwt.SubjectKey], email = email, norm
+		}
+		if len(raw) == 0 {
+			raw = nil
+		}
+	}
+
+	return Claims{
+		Issuer:          token.Issuer(),
+		Subject:         email,
+		Audience:        token.Audience(),
+		IssuedAt:        token.IssuedAt(),  // zero if not present
+		NotBefore:       token.NotBefore(), // zero if not present
+		ExpiresAt:       token.Expiration(),
+		JwtID:           token.JwtID(),
 		LicenseID:       licID,
-		Email:           token.Subject(),
-		Organization:    orgName,
 		AccountID:       int64(accID),
+		ParentAccountID: int64(parentID),
 		DeploymentID:    depUUID,
-		StorageCapacity: int64(storageCap),
+		Organization:    orgName,
+		Capacity:        Capacity(storageCap),
+		CapacityBytes:   storageBytes,
 		Plan:            plan,
-		IssuedAt:        iAt,
-		ExpiresAt:       token.Expiration(),
 		APIKey:          apiKey,
-		IsTrial:         isTrial,
+		Trial:           isTrial,
+		MaxNodes:        int64(nodes),
+		Features:        feats,
+		Extra:           extra,
+		raw:             raw,
 	}, nil
 }
 
+// reuseExtra returns m cleared, or a new map if m is nil, to hold the extra
+// claims of a license.
+func reuseExtra(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return make(map[string]interface{})
+	}
+	clear(m)
+	return m
+}
+
+// isEmail returns true if the subject s looks like an email address rather
+// than, e.g., a customer UUID.
+func isEmail(s string) bool {
+	at := strings.LastIndexByte(s, '@')
+	return at > 0 && at < len(s)-1 && !strings.ContainsAny(s, " \t\r\n")
+}
+
+// normalizeEmail returns the email address with its domain in Unicode NFC.
+// The local part is left as is, its interpretation is up to the mail server.
+func normalizeEmail(email string) string {
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return email
+	}
+	return email[:at+1] + norm.NFC.String(email[at+1:])
+}
+
+// keys returns the set of trusted keys. The returned set is never modified,
+// key changes replace it as a whole.
+func (lv *LicenseVerifier) keys() jwk.Set {
+	lv.mu.RLock()
+	defer lv.mu.RUnlock()
+	return lv.keySet
+}
+
+// keysWithOptions is like keys but also returns the parse options selecting
+// each key of the set, so that parse does not rebuild them on every call.
+func (lv *LicenseVerifier) keysWithOptions() (jwk.Set, [][]jwt.ParseOption) {
+	lv.mu.RLock()
+	keys, baseSet, baseOpts := lv.keySet, lv.baseSet, lv.baseOpts
+	lv.mu.RUnlock()
+	if keys == baseSet {
+		return keys, baseOpts
+	}
+
+	baseOpts = make([][]jwt.ParseOption, keys.Len())
+	for i := range baseOpts {
+		key, _ := keys.Get(i)
+		keyset := jwk.NewSet()
+		keyset.Add(key)
+		baseOpts[i] = []jwt.ParseOption{jwt.WithKeySet(keyset), jwt.UseDefaultKey(true), jwt.WithValidate(false)}
+	}
+	lv.mu.Lock()
+	if lv.keySet == keys {
+		lv.baseSet, lv.baseOpts = keys, baseOpts
+	}
+	lv.mu.Unlock()
+	return keys, baseOpts
+}
+
+// KeyIDs returns the key ID (kid) of each trusted key, in the order of the
+// key set, with an empty string for keys without ID. Keys read from PEM
+// have no ID, keys fetched from a remote key set keep theirs.
+func (lv *LicenseVerifier) KeyIDs() []string {
+	keys := lv.keys()
+	ids := make([]string, 0, keys.Len())
+	for i := 0; i < keys.Len(); i++ {
+		key, _ := keys.Get(i)
+		ids = append(ids, key.KeyID())
+	}
+	return ids
+}
+
+// SameKeys returns true if lv and other trust the same keys, compared by
+// their SHA-256 thumbprint (RFC 7638) and the algorithm they verify. The
+// order of the keys and their IDs are ignored.
+func (lv *LicenseVerifier) SameKeys(other *LicenseVerifier) bool {
+	a, err := keyThumbprints(lv.keys())
+	if err != nil {
+		return false
+	}
+	b, err := keyThumbprints(other.keys())
+	if err != nil {
+		return false
+	}
+	return maps.Equal(a, b)
+}
+
+// keyThumbprints returns the set of the thumbprints of keys, each followed by
+// the algorithm of the key.
+func keyThumbprints(keys jwk.Set) (map[string]struct{}, error) {
+	thumbprints := make(map[string]struct{}, keys.Len())
+	for i := 0; i < keys.Len(); i++ {
+		key, _ := keys.Get(i)
+		tp, err := key.Thumbprint(crypto.SHA256)
+		if err != nil {
+			return nil, err
+		}
+		thumbprints[string(tp)+key.Algorithm()] = struct{}{}
+	}
+	return thumbprints, nil
+}
+
+// parse verifies the signature of license against each key registered for
+// the algorithm in its header in turn and returns the token of the first one
+// that matches. Licenses in the general JWS JSON serialization may carry the
+// signatures of several authorities: they are trusted if any signature
+// matches. The claims are not validated. baseOpts holds the options selecting
+// each key, as returned by keysWithOptions.
+func parse(license string, keys jwk.Set, baseOpts [][]jwt.ParseOption, options []jwt.ParseOption) (jwt.Token, error) {
+	msg, err := jws.ParseString(license)
+	if err != nil {
+		return nil, err
+	}
+	if len(msg.Signatures()) == 0 {
+		return nil, errors.New("license isn't signed")
+	}
+	var names []string
+	algs := make(map[string]bool)
+	for _, sig := range msg.Signatures() {
+		alg := sig.ProtectedHeaders().Algorithm()
+		names = append(names, alg.String())
+		// Never trust an unsigned license, whatever keys are registered.
+		if alg != jwa.NoSignature {
+			algs[alg.String()] = true
+		}
+	}
+
+	var candidates []int
+	for i := 0; i < keys.Len(); i++ {
+		if key, _ := keys.Get(i); algs[key.Algorithm()] {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, strings.Join(names, ", "))
+	}
+
+	for _, i := range candidates {
+		key, _ := keys.Get(i)
+		var token jwt.Token
+		if token, err = parseWithKey(license, key, baseOpts[i], options); err == nil {
+			return token, nil
+		}
+	}
+	if len(candidates) > 1 {
+		return nil, fmt.Errorf("signature validation failed against all %d trusted keys", len(candidates))
+	}
+	return nil, err
+}
+
+// parseWithKey verifies the signature of license with key and returns its
+// token. baseOpts are the shared options selecting key, they come last so
+// that the caller options cannot override them and are never modified.
+// The claims are not validated.
+func parseWithKey(license string, key jwk.Key, baseOpts, options []jwt.ParseOption) (jwt.Token, error) {
+	if !strings.HasPrefix(strings.TrimSpace(license), "{") {
+		opts := baseOpts
+		if len(options) > 0 {
+			opts = append(options[:len(options):len(options)], baseOpts...)
+		}
+		return jwt.ParseString(license, opts...)
+	}
+
+	// jws skips the signatures whose key ID differs from the one of a JWK,
+	// pass the raw key for keys without ID to try them all.
+	var verifyKey interface{} = key
+	if key.KeyID() == "" {
+		if err := key.Raw(&verifyKey); err != nil {
+			return nil, err
+		}
+	}
+	payload, err := jws.Verify([]byte(license), jwa.SignatureAlgorithm(key.Algorithm()), verifyKey)
+	if err != nil {
+		return nil, err
+	}
+	opts := append(options[:len(options):len(options)], jwt.WithValidate(false))
+	return jwt.Parse(payload, opts...)
+}
+
 // Verify verifies the license key and validates the claims present in it.
+// Besides the jwt parse options, Verify accepts the options of this package
+// such as WithGracePeriod. Errors wrap one of:
+//
+//   - ErrInvalidSignature or ErrUnsupportedAlgorithm if the license isn't
+//     signed by a trusted key.
+//   - ErrLicenseExpired, ErrNotYetValid or ErrMalformedClaims if its claims
+//     are invalid.
+//   - ErrInvalidIssuer, ErrAccountNotAllowed or ErrPlanNotAllowed if it
+//     doesn't match WithExpectedIssuer, WithAllowedAccountIDs,
+//     WithAllowedParentAccountIDs or WithAllowedPlans.
+//   - ErrLicenseRevoked if it is revoked by WithRevocationList.
+//   - ErrCapacityExceeded if the usage set by WithUsage exceeds its capacity.
+//   - the error returned by a validator set by WithValidator.
+//
+// Expired, revoked, exceeded and rejected licenses, and those of accounts or
+// plans that aren't allowed, are reported with a *VerifyError. ErrInGracePeriod, and ErrLicenseExpired with
+// WithIgnoreExpiry, are returned along with the license info. VerifyContext
+// also returns the error of its context.
 func (lv *LicenseVerifier) Verify(license string, options ...jwt.ParseOption) (LicenseInfo, error) {
-	options = append(options, jwt.WithKeySet(lv.keySet), jwt.UseDefaultKey(true), jwt.WithValidate(true))
-	token, err := jwt.ParseString(license, options...)
+	_, li, err := lv.VerifyToken(license, options...)
+	return li, err
+}
+
+// VerifyToken is like Verify but also returns the parsed token, so that
+// claims not mapped to LicenseInfo can be read without parsing the license
+// again. The token is returned whenever the license info is, its signature
+// and claims have already been validated. It may be shared with other
+// callers through the cache set by WithCache and must not be modified.
+func (lv *LicenseVerifier) VerifyToken(license string, options ...jwt.ParseOption) (jwt.Token, LicenseInfo, error) {
+	return lv.verifyContext(context.Background(), license, options)
+}
+
+// VerifyInto is like Verify but stores the license info in out, so that it
+// can be reused across calls in hot paths. out is set to the license info
+// Verify would return, zero on most errors. The Features and Extra maps of out
+// are cleared and refilled rather than allocated again, they must not be
+// retained by the caller across calls.
+func (lv *LicenseVerifier) VerifyInto(license string, out *LicenseInfo, options ...jwt.ParseOption) error {
+	vo, options := splitOptions(options)
+	vo.features, vo.extra = out.Features, out.Extra
+	var err error
+	_, *out, err = lv.verifyObserved(context.Background(), license, vo, options)
+	return err
+}
+
+// VerifyContext is like Verify but returns ctx.Err() if ctx is done before
+// the verification completes.
+func (lv *LicenseVerifier) VerifyContext(ctx context.Context, license string, options ...jwt.ParseOption) (LicenseInfo, error) {
+	_, li, err := lv.verifyContext(ctx, license, options)
+	return li, err
+}
+
+// verifyContext implements VerifyContext and VerifyToken, reporting the
+// result to the observer set by WithObserver if any and its duration to the
+// latency recorder set by WithLatencyRecorder if any.
+func (lv *LicenseVerifier) verifyContext(ctx context.Context, license string, options []jwt.ParseOption) (jwt.Token, LicenseInfo, error) {
+	vo, options := splitOptions(options)
+	return lv.verifyObserved(ctx, license, vo, options)
+}
+
+// verifyObserved implements verifyContext with the options split by kind.
+func (lv *LicenseVerifier) verifyObserved(ctx context.Context, license string, vo verifyOptions, options []jwt.ParseOption) (jwt.Token, LicenseInfo, error) {
+	if vo.observer == nil && lv.opts.latency == nil {
+		return lv.verify(ctx, license, vo, options)
+	}
+	start := time.Now()
+	token, li, err := lv.verify(ctx, license, vo, options)
+	lv.observe(vo, time.Since(start), err)
+	return token, li, err
+}
+
+// observe reports the verification that took d and returned err to the
+// observer set by WithObserver and to the latency recorder, if any.
+func (lv *LicenseVerifier) observe(vo verifyOptions, d time.Duration, err error) {
+	if vo.observer != nil {
+		vo.observer.OnVerify(verifyResult(err), d)
+	}
+	if lv.opts.latency != nil {
+		lv.opts.latency.record(d)
+	}
+}
+
+// verify implements VerifyToken with the options split by kind.
+func (lv *LicenseVerifier) verify(ctx context.Context, license string, vo verifyOptions, options []jwt.ParseOption) (jwt.Token, LicenseInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, LicenseInfo{}, err
+	}
+
+	token, err := lv.parseCached(license, options, vo)
+	if err != nil {
+		return nil, LicenseInfo{}, signatureError(err)
+	}
+	return checkToken(ctx, license, token, vo, options)
+}
+
+// checkToken validates the claims of the token of license, whose signature
+// has been verified, and returns its license info.
+func checkToken(ctx context.Context, license string, token jwt.Token, vo verifyOptions, options []jwt.ParseOption) (jwt.Token, LicenseInfo, error) {
+	expiryErr, err := validateToken(ctx, license, token, vo, options)
+	if err != nil {
+		return nil, LicenseInfo{}, err
+	}
+
+	li, err := toLicenseInfo(license, token, vo)
+	if err != nil {
+		return nil, LicenseInfo{}, err
+	}
+	li.clock = vo.clock
+	if vo.accountIDs != nil {
+		if _, ok := vo.accountIDs[li.AccountID]; !ok {
+			return nil, LicenseInfo{}, &VerifyError{
+				Info: li,
+				Err:  fmt.Errorf("%w: account %d", ErrAccountNotAllowed, li.AccountID),
+			}
+		}
+	}
+	if vo.parentAccountIDs != nil {
+		if _, ok := vo.parentAccountIDs[li.ParentAccountID]; !ok {
+			return nil, LicenseInfo{}, &VerifyError{
+				Info: li,
+				Err:  fmt.Errorf("%w: parent account %d", ErrAccountNotAllowed, li.ParentAccountID),
+			}
+		}
+	}
+	if err = checkPlan(li, vo); err != nil {
+		return nil, LicenseInfo{}, &VerifyError{Info: li, Err: err}
+	}
+	if vo.revocationList.IsRevoked(li.DeploymentID) {
+		return nil, LicenseInfo{}, &VerifyError{
+			Info: li,
+			Err:  fmt.Errorf("%w: deployment %s", ErrLicenseRevoked, li.DeploymentID),
+		}
+	}
+	if err = checkUsage(li, vo); err != nil {
+		return nil, LicenseInfo{}, &VerifyError{Info: li, Err: err}
+	}
+	if err = runValidators(li, vo); err != nil {
+		return nil, LicenseInfo{}, &VerifyError{Info: li, Err: err}
+	}
+	if vo.onExpiryWarning != nil && expiryErr == nil {
+		if remaining := li.Remaining(); remaining <= vo.expiryWarning {
+			vo.onExpiryWarning(li, remaining)
+		}
+	}
+	return token, li, expiryErr
+}
+
+// validateToken validates the registered claims and the issuer of the token
+// of license. Licenses in their grace period, or expired with
+// WithIgnoreExpiry, are valid: the error wrapping ErrInGracePeriod or
+// ErrLicenseExpired to return along with their license info is returned as
+// expiryErr.
+func validateToken(ctx context.Context, license string, token jwt.Token, vo verifyOptions, options []jwt.ParseOption) (expiryErr, err error) {
+	validateOpts := validateOptions(ctx, options, vo)
+	if err = jwt.Validate(token, validateOpts...); err != nil {
+		if err != jwt.ErrTokenExpired() {
+			return nil, validationError(err)
+		}
+		switch {
+		case inGracePeriod(token, vo.gracePeriod, validateOpts):
+			expiryErr = fmt.Errorf("%w: expired on %s", ErrInGracePeriod, token.Expiration())
+		case vo.ignoreExpiry:
+			if err = validateIgnoringExpiry(token, validateOpts); err != nil {
+				return nil, validationError(err)
+			}
+			expiryErr = fmt.Errorf("%w: expired on %s", ErrLicenseExpired, token.Expiration())
+		default:
+			return nil, newVerifyError(license, token, vo, validationError(err))
+		}
+	}
+	if err = checkIssuer(ctx, token, vo); err != nil {
+		return nil, err
+	}
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	return expiryErr, nil
+}
+
+// signatureError wraps the error returned by parse in ErrInvalidSignature,
+// unless it is already an ErrUnsupportedAlgorithm error.
+func signatureError(err error) error {
+	if errors.Is(err, ErrUnsupportedAlgorithm) {
+		return err
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidSignature, err)
+}
+
+// validateOptions returns the options used to validate the claims of a
+// license: the jwt validate options in options and the clock set in vo.
+func validateOptions(ctx context.Context, options []jwt.ParseOption, vo verifyOptions) []jwt.ValidateOption {
+	validateOpts := []jwt.ValidateOption{jwt.WithContext(ctx)}
+	for _, o := range options {
+		if v, ok := o.(jwt.ValidateOption); ok {
+			validateOpts = append(validateOpts, v)
+		}
+	}
+	if vo.clock != nil {
+		validateOpts = append(validateOpts, jwt.WithClock(vo.clock))
+	}
+	return validateOpts
+}
+
+// checkIssuer returns an error wrapping ErrInvalidIssuer if token wasn't
+// issued by the issuer expected with WithExpectedIssuer.
+func checkIssuer(ctx context.Context, token jwt.Token, vo verifyOptions) error {
+	if vo.issuer == "" {
+		return nil
+	}
+	if err := jwt.ClaimValueIs(jwt.IssuerKey, vo.issuer).Validate(ctx, token); err != nil {
+		return fmt.Errorf("%w: expected %q, got %q", ErrInvalidIssuer, vo.issuer, token.Issuer())
+	}
+	return nil
+}
+
+// checkPlan returns an error wrapping ErrPlanNotAllowed if the plan of li
+// isn't one of the plans allowed by WithAllowedPlans.
+func checkPlan(li LicenseInfo, vo verifyOptions) error {
+	if vo.plans == nil {
+		return nil
+	}
+	if _, ok := vo.plans[canonicalPlan(li.Plan)]; !ok {
+		return fmt.Errorf("%w: plan %s", ErrPlanNotAllowed, li.Plan)
+	}
+	return nil
+}
+
+// checkUsage returns an error wrapping ErrCapacityExceeded if the usage set
+// by WithUsage exceeds the storage capacity of li.
+func checkUsage(li LicenseInfo, vo verifyOptions) error {
+	if !vo.checkUsage || li.WithinCapacity(vo.usedBytes) {
+		return nil
+	}
+	return fmt.Errorf("%w: %d bytes used, %d licensed", ErrCapacityExceeded, vo.usedBytes, li.CapacityBytes())
+}
+
+// runValidators runs the validators set by WithValidator on li and returns
+// the error of the first one failing.
+func runValidators(li LicenseInfo, vo verifyOptions) error {
+	for _, fn := range vo.validators {
+		if err := fn(li); err != nil {
+			return fmt.Errorf("license rejected by validator: %w", err)
+		}
+	}
+	return nil
+}
+
+// newVerifyError returns a VerifyError for err with the claims of token if
+// they are well-formed.
+func newVerifyError(license string, token jwt.Token, vo verifyOptions, err error) error {
+	li, infoErr := toLicenseInfo(license, token, vo)
+	if infoErr != nil {
+		return &VerifyError{Err: err}
+	}
+	li.clock = vo.clock
+	return &VerifyError{Info: li, Err: err}
+}
+
+// parseCached is like parse using the trusted keys, looking up and storing
+// the token in the cache set by WithCache if any.
+func (lv *LicenseVerifier) parseCached(license string, options []jwt.ParseOption, vo verifyOptions) (jwt.Token, error) {
+	keys, baseOpts := lv.keysWithOptions()
+	if vo.cache == nil {
+		return parse(license, keys, baseOpts, options)
+	}
+	clock := vo.clock
+	if clock == nil {
+		clock = defaultClock
+	}
+	if token, ok := vo.cache.get(license, keys, clock.Now()); ok {
+		return token, nil
+	}
+	token, err := parse(license, keys, baseOpts, options)
+	if err != nil {
+		return nil, err
+	}
+	vo.cache.put(license, keys, token, clock.Now())
+	return token, nil
+}
+
+// Inspect extracts the claims present in the license key without verifying its
+// signature or validating its expiry. The returned license info is untrusted:
+// it is meant for display purposes only, e.g. to show who an expired license
+// belongs to. Use Verify for anything else, with WithIgnoreExpiry to check
+// that an expired license is genuine.
+func Inspect(license string) (LicenseInfo, error) {
+	token, err := jwt.ParseString(license, jwt.WithValidate(false))
 	if err != nil {
-		return LicenseInfo{}, fmt.Errorf("failed to verify license: %s", err)
+		return LicenseInfo{}, fmt.Errorf("%w: %s", ErrMalformedClaims, err)
 	}
+	return toLicenseInfo(license, token, verifyOptions{})
+}
 
-	return toLicenseInfo(license, token)
+// inGracePeriod returns true if the expired token passes validation when its
+// expiry is extended by the grace period.
+func inGracePeriod(token jwt.Token, gracePeriod time.Duration, validateOpts []jwt.ValidateOption) bool {
+	if gracePeriod <= 0 {
+		return false
+	}
+	graced, err := token.Clone()
+	if err != nil {
+		return false
+	}
+	if err = graced.Set(jwt.ExpirationKey, token.Expiration().Add(gracePeriod)); err != nil {
+		return false
+	}
+	return jwt.Validate(graced, validateOpts...) == nil
+}
+
+// validateIgnoringExpiry validates the claims of the expired token other than
+// its expiry.
+func validateIgnoringExpiry(token jwt.Token, validateOpts []jwt.ValidateOption) error {
+	unexpired, err := withoutClaims(token, jwt.ExpirationKey)
+	if err != nil {
+		return err
+	}
+	return jwt.Validate(unexpired, validateOpts...)
 }
@@ -29,9 +29,16 @@ import (
 	"time"
 
 	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/minio/pkg/v3/env"
 	"github.com/minio/pkg/v3/licverifier"
 )
 
+// EnvLicensePublicKey is the environment variable holding a PEM encoded public
+// key. When set, it is used to verify licenses instead of the Subnet keys, e.g.
+// for on-prem Subnet mirrors with their own signing authority. Setting it to an
+// empty value is an error rather than a fallback to the Subnet keys.
+const EnvLicensePublicKey = "MINIO_LICENSE_PUBLIC_KEY"
+
 const (
 	publicKeyPath = "/downloads/license-pubkey.pem"
 
@@ -153,16 +160,10 @@ func (lv *LicenseValidator) downloadSubnetPublicKey() ([]byte, error) {
 
 // ParseLicense parses the license with the public key and return it's information.
 // Public key is downloaded from subnet. If there is an error downloading the public key
-// it will use the bundled public key instead.
+// it will use the bundled public key instead. Both are overridden by the public key
+// set in the MINIO_LICENSE_PUBLIC_KEY environment variable, if any.
 func (lv *LicenseValidator) ParseLicense(license string) (*licverifier.LicenseInfo, error) {
-	publicKey, e := lv.downloadSubnetPublicKey()
-	if e != nil {
-		// there was an issue getting the subnet public key
-		// use hardcoded public keys instead
-		publicKey = lv.offlinePubKey
-	}
-
-	lvr, e := licverifier.NewLicenseVerifier(publicKey)
+	lvr, e := lv.licenseVerifier()
 	if e != nil {
 		return nil, e
 	}
@@ -171,6 +172,30 @@ func (lv *LicenseValidator) ParseLicense(license string) (*licverifier.LicenseIn
 	return &li, e
 }
 
+// licenseVerifier returns a license verifier using the public key from the
+// environment if set, the subnet public key otherwise. The environment
+// variable set to an empty value is an error rather than a fallback.
+func (lv *LicenseValidator) licenseVerifier() (*licverifier.LicenseVerifier, error) {
+	if publicKey, ok := env.Lookup(EnvLicensePublicKey); ok {
+		if publicKey == "" {
+			return nil, fmt.Errorf("%s is set but empty", EnvLicensePublicKey)
+		}
+		lvr, e := licverifier.NewLicenseVerifier([]byte(publicKey))
+		if e != nil {
+			return nil, fmt.Errorf("invalid public key in %s: %w", EnvLicensePublicKey, e)
+		}
+		return lvr, nil
+	}
+
+	publicKey, e := lv.downloadSubnetPublicKey()
+	if e != nil {
+		// there was an issue getting the subnet public key
+		// use hardcoded public keys instead
+		publicKey = lv.offlinePubKey
+	}
+	return licverifier.NewLicenseVerifier(publicKey)
+}
+
 // ValidateLicense validates the license file.
 func (lv *LicenseValidator) ValidateLicense() (*licverifier.LicenseInfo, error) {
 	if lv.LicenseToken == "" && lv.LicenseFilePath == "" {
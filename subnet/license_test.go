@@ -0,0 +1,178 @@
+// Copyright (c) 2015-2024 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package subnet
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/minio/pkg/v3/env/envtest"
+	"github.com/minio/pkg/v3/licverifier"
+)
+
+// testSigner returns a license signer with a new key and the PEM encoded
+// public key verifying its licenses.
+func testSigner(t *testing.T) (*licverifier.LicenseSigner, []byte) {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls, err := licverifier.NewLicenseSigner(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("Failed to create license signer: %s", err)
+	}
+	if der, err = x509.MarshalPKIXPublicKey(&priv.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+	return ls, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+// testLicense returns a license signed by ls.
+func testLicense(t *testing.T, ls *licverifier.LicenseSigner) string {
+	t.Helper()
+	lic, err := ls.Sign(licverifier.LicenseInfo{
+		Subject:         "jane@example.com",
+		Organization:    "Acme",
+		AccountID:       42,
+		StorageCapacity: 100,
+		Plan:            "ENTERPRISE",
+		ExpiresAt:       time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("Failed to sign license: %s", err)
+	}
+	return lic
+}
+
+// unsetForTest unsets the environment variable key for the duration of the
+// test t.
+func unsetForTest(t *testing.T, key string) {
+	t.Helper()
+	envtest.Set(t, key, "")
+	os.Unsetenv(key)
+}
+
+// newTestValidator returns a license validator downloading the public key
+// from the URL pubKeyURL, with offlinePubKey as bundled public key.
+func newTestValidator(pubKeyURL string, offlinePubKey []byte) *LicenseValidator {
+	return &LicenseValidator{pubKeyURL: pubKeyURL, offlinePubKey: offlinePubKey}
+}
+
+func TestParseLicensePublicKeyFromEnv(t *testing.T) {
+	ls, pubKey := testSigner(t)
+	subnetLS, subnetPubKey := testSigner(t)
+	var downloads atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads.Add(1)
+		w.Write(subnetPubKey)
+	}))
+	defer ts.Close()
+	lv := newTestValidator(ts.URL, subnetPubKey)
+
+	envtest.Set(t, EnvLicensePublicKey, string(pubKey))
+	li, err := lv.ParseLicense(testLicense(t, ls))
+	if err != nil {
+		t.Fatalf("Expected license signed by the key of %s to pass verification but failed with %s", EnvLicensePublicKey, err)
+	}
+	if li.Organization != "Acme" || li.AccountID != 42 {
+		t.Fatalf("Unexpected license info %v", li)
+	}
+	if _, err = lv.ParseLicense(testLicense(t, subnetLS)); !errors.Is(err, licverifier.ErrInvalidSignature) {
+		t.Fatalf("Expected license signed by the subnet key to fail verification, got %v", err)
+	}
+	if n := downloads.Load(); n != 0 {
+		t.Fatalf("Expected the subnet public key not to be downloaded, got %d downloads", n)
+	}
+}
+
+func TestParseLicenseInvalidPublicKeyFromEnv(t *testing.T) {
+	ls, pubKey := testSigner(t)
+	lv := newTestValidator("http://127.0.0.1:0", pubKey)
+
+	envtest.Set(t, EnvLicensePublicKey, "not a PEM key")
+	_, err := lv.ParseLicense(testLicense(t, ls))
+	if err == nil || !strings.Contains(err.Error(), EnvLicensePublicKey) || errors.Unwrap(err) == nil {
+		t.Fatalf("Expected a wrapped error naming %s, got %v", EnvLicensePublicKey, err)
+	}
+}
+
+func TestParseLicenseSubnetPublicKey(t *testing.T) {
+	unsetForTest(t, EnvLicensePublicKey)
+	ls, pubKey := testSigner(t)
+	offlineLS, offlinePubKey := testSigner(t)
+
+	// The downloaded key is used when available.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(pubKey)
+	}))
+	defer ts.Close()
+	lv := newTestValidator(ts.URL, offlinePubKey)
+	if _, err := lv.ParseLicense(testLicense(t, ls)); err != nil {
+		t.Fatalf("Expected license signed by the downloaded key to pass verification but failed with %s", err)
+	}
+	if _, err := lv.ParseLicense(testLicense(t, offlineLS)); !errors.Is(err, licverifier.ErrInvalidSignature) {
+		t.Fatalf("Expected license signed by the bundled key to fail verification, got %v", err)
+	}
+
+	// The bundled key is used when the download fails.
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	lv = newTestValidator(failing.URL, offlinePubKey)
+	if _, err := lv.ParseLicense(testLicense(t, offlineLS)); err != nil {
+		t.Fatalf("Expected license signed by the bundled key to pass verification but failed with %s", err)
+	}
+	if _, err := lv.ParseLicense(testLicense(t, ls)); !errors.Is(err, licverifier.ErrInvalidSignature) {
+		t.Fatalf("Expected license signed by another key to fail verification, got %v", err)
+	}
+}
+
+func TestParseLicenseEmptyPublicKeyFromEnv(t *testing.T) {
+	ls, pubKey := testSigner(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(pubKey)
+	}))
+	defer ts.Close()
+	lv := newTestValidator(ts.URL, pubKey)
+
+	// An empty key is an error rather than a fallback to the subnet key.
+	for i, value := range []string{"", " ", " \t\n "} {
+		envtest.Set(t, EnvLicensePublicKey, value)
+		_, err := lv.ParseLicense(testLicense(t, ls))
+		if err == nil || !strings.Contains(err.Error(), EnvLicensePublicKey+" is set but empty") {
+			t.Fatalf("%d: Expected empty %s error, got %v", i+1, EnvLicensePublicKey, err)
+		}
+	}
+}